@@ -0,0 +1,280 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/20 14:00
+ */
+
+package cuckoo
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+
+	"github.com/dgryski/go-metro"
+)
+
+// maxSelectorBits keeps fingerprints of all selectors of a key in a small array
+const maxSelectorBits = 4
+
+// KeyStore is the reverse map of an AdaptiveFilter from slots to keys stored in them,
+// usually kept beside the backing store that the filter guards
+type KeyStore interface {
+	// Key return key stored in slot of bucket
+	Key(bucket, slot uint) []byte
+	// SetKey record key is stored in slot of bucket
+	SetKey(bucket, slot uint, key []byte)
+	// DeleteKey record slot of bucket is empty
+	DeleteKey(bucket, slot uint)
+}
+
+type slotPos struct {
+	bucket, slot uint
+}
+
+// MapKeyStore is a KeyStore in memory
+type MapKeyStore struct {
+	keys map[slotPos][]byte
+}
+
+// NewMapKeyStore return an empty MapKeyStore
+func NewMapKeyStore() *MapKeyStore {
+	return &MapKeyStore{keys: make(map[slotPos][]byte)}
+}
+
+// Key return key stored in slot of bucket
+func (m *MapKeyStore) Key(bucket, slot uint) []byte {
+	return m.keys[slotPos{bucket, slot}]
+}
+
+// SetKey record key is stored in slot of bucket, key is kept without copy
+func (m *MapKeyStore) SetKey(bucket, slot uint, key []byte) {
+	m.keys[slotPos{bucket, slot}] = key
+}
+
+// DeleteKey record slot of bucket is empty
+func (m *MapKeyStore) DeleteKey(bucket, slot uint) {
+	delete(m.keys, slotPos{bucket, slot})
+}
+
+// AdaptiveFilter is an adaptive cuckoo filter, which removes false positives reported by Adapt.
+// Each slot stores tag | selector<<bitsPerItem, the selector choose which of 2^selectorBits hash functions
+// derives the tag from the key. When a key is a false positive, Adapt switch the selector of colliding slots,
+// and recompute their tags from original keys found in the KeyStore, so the same key stops matching them.
+// Original keys are also used to find alternate buckets of tags kicked out, tags move with their selectors.
+// It is not safe for concurrent use
+type AdaptiveFilter struct {
+	table        *SingleTable
+	keys         KeyStore
+	bitsPerItem  uint
+	selectorBits uint
+	fpMask       uint32
+	numItems     uint
+	// victim is the key failing to be inserted, it is kept as a whole
+	victim      []byte
+	adaptations uint64
+}
+
+// NewAdaptiveFilter return an AdaptiveFilter recording keys of slots in keys,
+// bitsPerItem is bits of tag excluding selector, see NewFilter for the other parameters
+func NewAdaptiveFilter(keys KeyStore, selectorBits, tagsPerBucket, bitsPerItem, maxNumKeys uint) (*AdaptiveFilter, error) {
+	if err := (Config{TagsPerBucket: tagsPerBucket, BitsPerItem: bitsPerItem, TableType: TableTypeSingle}).Validate(); err != nil {
+		return nil, err
+	}
+	if selectorBits == 0 || selectorBits > maxSelectorBits || bitsPerItem+selectorBits > 32 {
+		return nil, fmt.Errorf("selector bits should be within [1, %d] and fit in 32 bits with tag but got %d", maxSelectorBits, selectorBits)
+	}
+	table := NewSingleTable()
+	if err := table.Init(tagsPerBucket, bitsPerItem+selectorBits, numBucketsFor(tagsPerBucket, maxNumKeys), nil); err != nil {
+		return nil, err
+	}
+	return &AdaptiveFilter{
+		table:        table,
+		keys:         keys,
+		bitsPerItem:  bitsPerItem,
+		selectorBits: selectorBits,
+		fpMask:       (1 << bitsPerItem) - 1,
+	}, nil
+}
+
+// indexTag return bucket index and tag of selector 0 of key, which decide the bucket pair like Filter
+func (a *AdaptiveFilter) indexTag(key []byte) (uint, uint32) {
+	h := metro.Hash64(key, 1337)
+	return uint(h>>32) & (a.table.numBuckets - 1), uint32(h)%a.fpMask + 1
+}
+
+// tag return tag of key derived by hash function of selector s
+func (a *AdaptiveFilter) tag(key []byte, s uint32) uint32 {
+	if s == 0 {
+		_, tag := a.indexTag(key)
+		return tag
+	}
+	return uint32(metro.Hash64(key, 1337+uint64(s)))%a.fpMask + 1
+}
+
+func (a *AdaptiveFilter) altIndex(index uint, tag uint32) uint {
+	return (index ^ uint(tag*0x5bd1e995)) & (a.table.numBuckets - 1)
+}
+
+// match return if slot stores tag of key, tags of key are computed lazily into tags by selector
+func (a *AdaptiveFilter) match(slot uint32, key []byte, tags *[1 << maxSelectorBits]uint32) bool {
+	tag := slot & a.fpMask
+	if tag == 0 {
+		return false
+	}
+	s := slot >> a.bitsPerItem
+	// tags are never 0
+	if tags[s] == 0 {
+		tags[s] = a.tag(key, s)
+	}
+	return tags[s] == tag
+}
+
+func (a *AdaptiveFilter) insertToEmpty(i uint, slot uint32, key []byte) bool {
+	for j := uint(0); j < a.table.kTagsPerBucket; j++ {
+		if a.table.ReadTag(i, j)&a.fpMask == 0 {
+			a.table.WriteTag(i, j, slot)
+			a.keys.SetKey(i, j, key)
+			a.numItems++
+			return true
+		}
+	}
+	return false
+}
+
+// Add add a key into filter, return false when filter is full
+func (a *AdaptiveFilter) Add(key []byte) bool {
+	if a.victim != nil {
+		return false
+	}
+	i1, tag := a.indexTag(key)
+	i2 := a.altIndex(i1, tag)
+	if a.insertToEmpty(i1, tag, key) || a.insertToEmpty(i2, tag, key) {
+		return true
+	}
+
+	cur, slot := i2, tag
+	for count := uint(0); count < kMaxCuckooCount; count++ {
+		r := uint(rand.Int31()) % a.table.kTagsPerBucket
+		oldSlot, oldKey := a.table.ReadTag(cur, r), a.keys.Key(cur, r)
+		a.table.WriteTag(cur, r, slot)
+		a.keys.SetKey(cur, r, key)
+		slot, key = oldSlot, oldKey
+
+		_, tag = a.indexTag(key)
+		cur = a.altIndex(cur, tag)
+		if a.insertToEmpty(cur, slot, key) {
+			return true
+		}
+	}
+	a.victim = key
+	return true
+}
+
+// Contain return if filter contains a key
+func (a *AdaptiveFilter) Contain(key []byte) bool {
+	var tags [1 << maxSelectorBits]uint32
+	i1, tag := a.indexTag(key)
+	i2 := a.altIndex(i1, tag)
+	tags[0] = tag
+	for j := uint(0); j < a.table.kTagsPerBucket; j++ {
+		if a.match(a.table.ReadTag(i1, j), key, &tags) || a.match(a.table.ReadTag(i2, j), key, &tags) {
+			return true
+		}
+	}
+	return a.victim != nil && bytes.Equal(a.victim, key)
+}
+
+// rangeMatch call fn with bucket, slot and content of slots matching key in its bucket pair
+func (a *AdaptiveFilter) rangeMatch(key []byte, fn func(i, j uint, slot uint32) bool) {
+	var tags [1 << maxSelectorBits]uint32
+	i1, tag := a.indexTag(key)
+	i2 := a.altIndex(i1, tag)
+	tags[0] = tag
+	for _, i := range [2]uint{i1, i2} {
+		for j := uint(0); j < a.table.kTagsPerBucket; j++ {
+			if slot := a.table.ReadTag(i, j); a.match(slot, key, &tags) && !fn(i, j, slot) {
+				return
+			}
+		}
+		if i1 == i2 {
+			return
+		}
+	}
+}
+
+// Delete delete key from filter, return false when key not exist.
+// Slots are checked against keys in the KeyStore, so a false positive never deletes other keys
+func (a *AdaptiveFilter) Delete(key []byte) bool {
+	deleted := false
+	a.rangeMatch(key, func(i, j uint, _ uint32) bool {
+		if !bytes.Equal(a.keys.Key(i, j), key) {
+			return true
+		}
+		a.table.WriteTag(i, j, 0)
+		a.keys.DeleteKey(i, j)
+		a.numItems--
+		deleted = true
+		return false
+	})
+	if !deleted {
+		if a.victim == nil || !bytes.Equal(a.victim, key) {
+			return false
+		}
+		a.victim = nil
+		return true
+	}
+	if a.victim != nil {
+		victim := a.victim
+		a.victim = nil
+		a.Add(victim)
+	}
+	return true
+}
+
+// Adapt tell filter key is a false positive, slots matching it are switched to the next selector,
+// so that the key is not contained any more with high probability. It return num of slots adapted
+func (a *AdaptiveFilter) Adapt(key []byte) int {
+	n := 0
+	a.rangeMatch(key, func(i, j uint, slot uint32) bool {
+		orig := a.keys.Key(i, j)
+		if bytes.Equal(orig, key) {
+			return true
+		}
+		s := (slot>>a.bitsPerItem + 1) & (1<<a.selectorBits - 1)
+		a.table.WriteTag(i, j, a.tag(orig, s)|s<<a.bitsPerItem)
+		n++
+		return true
+	})
+	a.adaptations += uint64(n)
+	return n
+}
+
+// Adaptations return num of slots adapted since filter is created
+func (a *AdaptiveFilter) Adaptations() uint64 {
+	return a.adaptations
+}
+
+// Size return num of items that filter store
+func (a *AdaptiveFilter) Size() uint {
+	if a.victim != nil {
+		return a.numItems + 1
+	}
+	return a.numItems
+}
+
+// LoadFactor return current filter's loadFactor
+func (a *AdaptiveFilter) LoadFactor() float64 {
+	return float64(a.Size()) / float64(a.table.SizeInTags())
+}
+
+// SizeInBytes return bytes occupancy of filter's table, excluding the KeyStore
+func (a *AdaptiveFilter) SizeInBytes() uint {
+	return a.table.SizeInBytes()
+}
+
+// Reset reset the filter, keys recorded in the KeyStore should be cleared by caller
+func (a *AdaptiveFilter) Reset() {
+	a.table.Reset()
+	a.numItems = 0
+	a.victim = nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/20 14:00
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func uint64Key(i uint64) []byte {
+	key := make([]byte, 8)
+	binary.LittleEndian.PutUint64(key, i)
+	return key
+}
+
+func TestAdaptiveFilter(t *testing.T) {
+	if _, err := NewAdaptiveFilter(NewMapKeyStore(), 5, 4, 8, 1000); err == nil {
+		t.Fatalf("Expected error for too many selector bits")
+	}
+	a, err := NewAdaptiveFilter(NewMapKeyStore(), 2, 4, 8, 3800)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	// fill to a high load, so that keys are kicked around
+	var n uint64
+	for ; n < 4000 && a.Add(uint64Key(n)); n++ {
+	}
+	if a.LoadFactor() < 0.9 {
+		t.Fatalf("Expected high load factor, instead %v", a.LoadFactor())
+	}
+	for i := uint64(0); i < n; i++ {
+		if !a.Contain(uint64Key(i)) {
+			t.Fatalf("Expected contain key %d", i)
+		}
+	}
+
+	// adaptation never breaks true positives
+	for i := n; i < n+10000; i++ {
+		if key := uint64Key(i); a.Contain(key) {
+			a.Adapt(key)
+		}
+	}
+	if a.Adaptations() == 0 {
+		t.Fatalf("Expected adaptations")
+	}
+	for i := uint64(0); i < n; i++ {
+		if a.Adapt(uint64Key(i)) != 0 && !a.Contain(uint64Key(i)) {
+			t.Fatalf("Expected contain key %d after adaptation", i)
+		}
+	}
+
+	size := a.Size()
+	for i := uint64(0); i < 100; i++ {
+		if !a.Delete(uint64Key(i)) {
+			t.Fatalf("Expected delete key %d", i)
+		}
+	}
+	if a.Size() != size-100 || a.Delete(uint64Key(0)) {
+		t.Fatalf("Expected size %d after delete, instead %d", size-100, a.Size())
+	}
+	for i := uint64(100); i < n; i++ {
+		if !a.Contain(uint64Key(i)) {
+			t.Fatalf("Expected contain key %d after delete", i)
+		}
+	}
+}
+
+func TestAdaptiveFilterFalsePositiveRate(t *testing.T) {
+	const numKeys, numQueries = 3000, 200000
+	a, _ := NewAdaptiveFilter(NewMapKeyStore(), 2, 4, 6, numKeys)
+	for i := uint64(0); i < numKeys; i++ {
+		a.Add(uint64Key(i))
+	}
+
+	// negative queries concentrated on few hot keys
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.2, 1, 100000)
+	queries := make([][]byte, numQueries)
+	for i := range queries {
+		queries[i] = uint64Key(numKeys + zipf.Uint64())
+	}
+	run := func(adapt bool) (fp int) {
+		for _, key := range queries {
+			if a.Contain(key) {
+				fp++
+				if adapt {
+					a.Adapt(key)
+				}
+			}
+		}
+		return
+	}
+	before := run(false)
+	adapted := run(true)
+	after := run(false)
+	t.Logf("false positive rate: %v before, %v while adapting, %v after",
+		float64(before)/numQueries, float64(adapted)/numQueries, float64(after)/numQueries)
+	if before == 0 || adapted*3 > before || after*10 > before {
+		t.Fatalf("Expected adaptation reduce false positives, %d before, %d while adapting, %d after", before, adapted, after)
+	}
+	for i := uint64(0); i < numKeys; i++ {
+		if !a.Contain(uint64Key(i)) {
+			t.Fatalf("Expected contain key %d", i)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 10:20
+ */
+
+package cuckoo
+
+import (
+	"fmt"
+	"math"
+)
+
+// Config is the parameters of a filter
+type Config struct {
+	// TagsPerBucket is num of tags for each bucket, which is b in paper
+	TagsPerBucket uint
+	// BitsPerItem is num of bits for each item, which is length of tag(fingerprint)
+	BitsPerItem uint
+	// TableType is TableTypeSingle, TableTypePacked or TableTypeVacuum
+	TableType uint
+}
+
+// Validate return an error if the parameters can't make a filter
+func (c Config) Validate() error {
+	// tags per bucket is encoded in a byte
+	if c.TagsPerBucket == 0 || c.TagsPerBucket > 255 {
+		return fmt.Errorf("tags per bucket should be within [1, 255] but got %d", c.TagsPerBucket)
+	}
+	if c.BitsPerItem < 2 || c.BitsPerItem > 32 {
+		return fmt.Errorf("bits per item should be within [2, 32] but got %d", c.BitsPerItem)
+	}
+	switch c.TableType {
+	case TableTypeSingle, TableTypeVacuum:
+	case TableTypePacked:
+		if c.TagsPerBucket != tagsPerPTable {
+			return fmt.Errorf("packed table requires %d tags per bucket but got %d", tagsPerPTable, c.TagsPerBucket)
+		}
+		if c.BitsPerItem < cFpSize {
+			return fmt.Errorf("packed table requires at least %d bits per item but got %d", cFpSize, c.BitsPerItem)
+		}
+	default:
+		return fmt.Errorf("unknown table type %d", c.TableType)
+	}
+	return nil
+}
+
+// BuildFilter return a filter containing all keys, which is built offline instead of by incremental Add.
+// Table is sized to the fewest buckets whose load is below buildMaxLoad, which is a power of two except
+// TableTypeVacuum, and fingerprints are placed with sorted greedy insertion followed by bounded augmenting
+// path search, which finds a placement when one exists below that load in practice.
+// So the load factor can be higher than the one NewFilter plans for.
+// The achieved load is reported by LoadFactor and the num of stash entries by StashSize.
+// Duplicate keys are stored once, since more than 2*TagsPerBucket+1 copies of a key can never be placed.
+// ErrFilterFull is returned when keys can't be placed even in buildMaxGrowth times the fewest buckets
+func BuildFilter(keys [][]byte, cfg Config) (*Filter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	hashes := make([]uint64, len(keys))
+	// only used for hashing
+	f := &Filter{}
+	for i, key := range keys {
+		hashes[i] = f.hash(key)
+	}
+	hashes = uniqueHashes(hashes)
+
+	// sizes above the load cuckoo hashing can reach are skipped, proving them infeasible is slow
+	minBuckets := uint(math.Ceil(float64(len(hashes)) / float64(cfg.TagsPerBucket) / buildMaxLoad(cfg.TagsPerBucket)))
+	numBuckets := getNextPow2(uint64(minBuckets))
+	if cfg.TableType == TableTypeVacuum {
+		numBuckets = vacuumNumBuckets(minBuckets, cfg.TagsPerBucket)
+	}
+	if numBuckets == 0 {
+		numBuckets = 1
+	}
+	for {
+		table := getTable(cfg.TableType).(table)
+		if err := table.Init(cfg.TagsPerBucket, cfg.BitsPerItem, numBuckets, nil); err != nil {
+			return nil, err
+		}
+		f = &Filter{table: table, vacuum: asVacuum(table)}
+		if f.place(hashes) {
+			return f, nil
+		}
+		if numBuckets >= buildMaxGrowth*minBuckets {
+			return nil, fmt.Errorf("%w: can't place %d keys in %d buckets", ErrFilterFull, len(hashes), numBuckets)
+		}
+		if cfg.TableType == TableTypeVacuum {
+			numBuckets = vacuumNumBuckets(numBuckets+numBuckets/32+1, cfg.TagsPerBucket)
+			continue
+		}
+		numBuckets <<= 1
+	}
+}
+
+const (
+	// buildMaxGrowth bounds num of buckets BuildFilter tries, placement of distinct keys rarely fails
+	// once load is below buildMaxLoad, so reaching it means hashes collide
+	buildMaxGrowth = 8
+	// buildMaxSearch bounds num of buckets visited by augmenting path search of a fingerprint
+	buildMaxSearch = 1 << 16
+	// buildSearchPerItem bounds num of buckets visited by augmenting path search of a placement
+	// to this times num of fingerprints, besides buildMaxSearch
+	buildSearchPerItem = 16
+)
+
+// buildMaxLoad return the load factor a placement of random fingerprints can almost always reach,
+// which is a bit below the threshold of cuckoo hashing with tagsPerBucket slots
+func buildMaxLoad(tagsPerBucket uint) float64 {
+	switch tagsPerBucket {
+	case 1:
+		return 0.49
+	case 2:
+		return 0.89
+	case 3:
+		return 0.95
+	case 4:
+		return 0.975
+	case 5, 6, 7:
+		return 0.99
+	default:
+		return 0.995
+	}
+}
+
+// uniqueHashes sort hashes with LSD radix sort and remove duplicates in place
+func uniqueHashes(hashes []uint64) []uint64 {
+	const radixBits = 11
+	const radixMask = 1<<radixBits - 1
+	tmp := make([]uint64, len(hashes))
+	for shift := uint(0); shift < 64; shift += radixBits {
+		var count [radixMask + 2]int
+		for _, h := range hashes {
+			count[(h>>shift)&radixMask+1]++
+		}
+		for i := 1; i < len(count); i++ {
+			count[i] += count[i-1]
+		}
+		for _, h := range hashes {
+			d := (h >> shift) & radixMask
+			tmp[count[d]] = h
+			count[d]++
+		}
+		hashes, tmp = tmp, hashes
+	}
+	n := 0
+	for i, h := range hashes {
+		if i == 0 || h != hashes[n-1] {
+			hashes[n] = h
+			n++
+		}
+	}
+	return hashes[:n]
+}
+
+// builder place fingerprints offline, keeping num of tags of each bucket
+type builder struct {
+	f      *Filter
+	counts []uint8
+
+	// breadth-first search state, a bucket is visited when its epoch equals current one
+	epoch        uint32
+	visited      []uint32
+	parentBucket []uint32
+	parentTag    []uint32
+	queue        []uint32
+	tags         []uint32
+	// budget is num of buckets augmenting path search can still visit
+	budget int
+}
+
+// place insert all hashes into empty f, at most one of them can end in the victim,
+// return false when more than one can't be placed
+func (f *Filter) place(hashes []uint64) bool {
+	batch := make([]batchItem, len(hashes))
+	for i, h := range hashes {
+		index, tag := f.indexTagHash(h)
+		batch[i] = batchItem{index: uint32(index), tag: tag, pos: uint32(i)}
+	}
+	return f.placeBatch(batch)
+}
+
+// repack place all fingerprints of f together with extra ones again into an empty table, it return false
+// and leave f unchanged when more than one can't be placed. Placement is found at loads
+// where random kicks of Add may fail
+func (f *Filter) repack(extra ...batchItem) bool {
+	batch := extra
+	f.Range(func(bucket, _ uint, tag uint32) bool {
+		batch = append(batch, batchItem{index: uint32(bucket), tag: tag})
+		return true
+	})
+	nt := f.table.clone()
+	nf := &Filter{table: nt, vacuum: asVacuum(nt)}
+	nf.table.Reset()
+	if !nf.placeBatch(batch) {
+		return false
+	}
+	f.table, f.vacuum, f.numItems, f.victim = nf.table, nf.vacuum, nf.numItems, nf.victim
+	return true
+}
+
+// placeBatch insert tags at bucket pairs of their index into empty f, see place
+func (f *Filter) placeBatch(batch []batchItem) bool {
+	numBuckets := f.table.NumBuckets()
+	tagsPerBucket := f.table.TagsPerBucket()
+	b := &builder{
+		f:            f,
+		counts:       make([]uint8, numBuckets),
+		visited:      make([]uint32, numBuckets),
+		parentBucket: make([]uint32, numBuckets),
+		parentTag:    make([]uint32, numBuckets),
+		tags:         make([]uint32, tagsPerBucket),
+		budget:       buildMaxSearch + buildSearchPerItem*len(batch),
+	}
+
+	// sorted greedy: sweep buckets in order and put each fingerprint into the emptier of its pair
+	batch = radixSortBatch(batch, make([]batchItem, len(batch)), numBuckets)
+	var pending []batchItem
+	for _, p := range batch {
+		i1 := uint(p.index)
+		i2 := f.altIndex(i1, p.tag)
+		i := i1
+		if b.counts[i2] < b.counts[i1] {
+			i = i2
+		}
+		if uint(b.counts[i]) < tagsPerBucket {
+			b.insert(i, p.tag)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+
+	for _, p := range pending {
+		if b.augment(uint(p.index), p.tag) {
+			continue
+		}
+		if f.victim.used {
+			return false
+		}
+		f.victim.index = uint(p.index)
+		f.victim.tag = p.tag
+		f.victim.used = true
+	}
+	return true
+}
+
+func (b *builder) insert(i uint, tag uint32) {
+	b.f.table.InsertTagToBucket(i, tag, false, nil)
+	b.counts[i]++
+	b.f.numItems++
+}
+
+// augment search the shortest path of moves from bucket pair of tag to a bucket with free slot,
+// and shift fingerprints along it to make room for tag. It gives up after visiting buildMaxSearch buckets
+// or the budget of placement, return false then
+func (b *builder) augment(i1 uint, tag uint32) bool {
+	f := b.f
+	tagsPerBucket := f.table.TagsPerBucket()
+	b.epoch++
+	b.queue = b.queue[:0]
+	for _, i := range []uint{i1, f.altIndex(i1, tag)} {
+		if uint(b.counts[i]) < tagsPerBucket {
+			b.insert(i, tag)
+			return true
+		}
+		if b.visited[i] != b.epoch {
+			b.visited[i] = b.epoch
+			b.parentBucket[i] = uint32(i)
+			b.queue = append(b.queue, uint32(i))
+		}
+	}
+
+	for head := 0; head < len(b.queue) && head < buildMaxSearch && b.budget > 0; head++ {
+		b.budget--
+		u := uint(b.queue[head])
+		f.table.ReadTagsFromBucket(u, b.tags)
+		for _, t := range b.tags {
+			v := f.altIndex(u, t)
+			if b.visited[v] == b.epoch {
+				continue
+			}
+			b.visited[v] = b.epoch
+			b.parentBucket[v] = uint32(u)
+			b.parentTag[v] = t
+			if uint(b.counts[v]) < tagsPerBucket {
+				b.insert(b.shift(v), tag)
+				return true
+			}
+			b.queue = append(b.queue, uint32(v))
+		}
+	}
+	return false
+}
+
+// shift move fingerprints along the path ending at bucket v, which has a free slot,
+// and return the first bucket of the path, which has a free slot then
+func (b *builder) shift(v uint) uint {
+	for {
+		u := uint(b.parentBucket[v])
+		t := b.parentTag[v]
+		b.f.table.DeleteTagFromBucket(u, t)
+		b.f.table.InsertTagToBucket(v, t, false, nil)
+		b.counts[u]--
+		b.counts[v]++
+		if uint(b.parentBucket[u]) == u {
+			return u
+		}
+		v = u
+	}
+}
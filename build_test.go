@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 10:20
+ */
+
+package cuckoo
+
+import (
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestBuildFilter(t *testing.T) {
+	for _, c := range []struct {
+		cfg     Config
+		numKeys int
+	}{
+		{Config{TagsPerBucket: 2, BitsPerItem: 12, TableType: TableTypeSingle}, 14000},
+		{Config{TagsPerBucket: 4, BitsPerItem: 12, TableType: TableTypeSingle}, 31900},
+		{Config{TagsPerBucket: 4, BitsPerItem: 12, TableType: TableTypePacked}, 31900},
+		{Config{TagsPerBucket: 8, BitsPerItem: 12, TableType: TableTypeSingle}, 32600},
+		{Config{TagsPerBucket: 4, BitsPerItem: 12, TableType: TableTypeVacuum}, 31900},
+	} {
+		keys := make([][]byte, c.numKeys)
+		for i := range keys {
+			keys[i] = make([]byte, 32)
+			_, _ = io.ReadFull(rand.Reader, keys[i])
+		}
+
+		cf, err := BuildFilter(keys, c.cfg)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		if cf.Size() != uint(len(keys)) {
+			t.Fatalf("Expected count = %d, instead count = %d, config %+v", len(keys), cf.Size(), c.cfg)
+		}
+		// NewFilter would double these tables since load exceeds maxLoadFactor
+		if cf.LoadFactor() <= maxLoadFactor(c.cfg.TagsPerBucket) {
+			t.Errorf("Expected load factor over %v, instead %v, config %+v", maxLoadFactor(c.cfg.TagsPerBucket), cf.LoadFactor(), c.cfg)
+		}
+		nf := NewFilter(c.cfg.TagsPerBucket, c.cfg.BitsPerItem, uint(len(keys)), c.cfg.TableType)
+		for _, key := range keys {
+			nf.Add(key)
+		}
+		if cf.LoadFactor() <= nf.LoadFactor() {
+			t.Errorf("Expected load factor over %v of NewFilter, instead %v, config %+v", nf.LoadFactor(), cf.LoadFactor(), c.cfg)
+		}
+		if cf.StashSize() > 1 {
+			t.Errorf("Unexpected stash size %d", cf.StashSize())
+		}
+		for _, key := range keys {
+			if !cf.Contain(key) {
+				t.Fatalf("Expected contain, config %+v", c.cfg)
+			}
+		}
+		for _, key := range keys {
+			if !cf.Delete(key) {
+				t.Fatalf("Expected delete, config %+v", c.cfg)
+			}
+		}
+	}
+
+	// more copies of a key than its bucket pair holds
+	keys := make([][]byte, 100, 120)
+	for i := range keys {
+		keys[i] = []byte{byte(i)}
+	}
+	for i := 0; i < 20; i++ {
+		keys = append(keys, []byte("dup"))
+	}
+	for _, table := range testTableType {
+		cf, err := BuildFilter(keys, Config{TagsPerBucket: 4, BitsPerItem: 12, TableType: table})
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		if cf.Size() != 101 || !cf.Contain([]byte("dup")) || !cf.Delete([]byte("dup")) || cf.Contain([]byte("dup")) {
+			t.Fatalf("Expected duplicate keys stored once, size %d", cf.Size())
+		}
+	}
+
+	if _, err := BuildFilter(nil, Config{TagsPerBucket: 256, BitsPerItem: 8}); err == nil {
+		t.Errorf("Expected error for 256 tags per bucket")
+	}
+	if _, err := BuildFilter(nil, Config{TagsPerBucket: 2, BitsPerItem: 8, TableType: TableTypePacked}); err == nil {
+		t.Errorf("Expected error for packed table with 2 tags per bucket")
+	}
+	cf, err := BuildFilter(nil, Config{TagsPerBucket: 4, BitsPerItem: 8})
+	if err != nil || cf.Size() != 0 {
+		t.Fatalf("Expected empty filter, err %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 21:00
+ */
+
+// Package cluster partition keys across filter nodes served by package cuckoorpc.
+//
+// A filter can't enumerate its keys, and a fingerprint is only meaningful in a table of the same size,
+// so keys are not spread over nodes directly. Instead keys are hashed into a fixed num of partitions,
+// each partition is a filter of the same parameters, and whole partitions are placed on nodes by a
+// consistent-hash ring. When a node is added or removed, only partitions whose owner changes are migrated,
+// by iterating fingerprints of the old filter into a new one on the new owner, see cuckoo.Filter.Merge
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dgryski/go-metro"
+	cuckoo "github.com/linvon/cuckoo-filter"
+	"github.com/linvon/cuckoo-filter/cuckoorpc"
+)
+
+// partitionSeed is the hash seed of keys to partitions. It differs from the seed of filter's hash,
+// otherwise keys of a partition would share low bits of the hash deriving their bucket index
+const partitionSeed = 0x70617274
+
+// Config is the parameters of a cluster
+type Config struct {
+	// Name is prefix of names of partition filters on nodes
+	Name string
+	// Partitions is num of partitions, which can't be changed once data is added
+	Partitions uint
+	// Replicas is num of points of each node on the ring, default to 64
+	Replicas uint
+	// TagsPerBucket, BitsPerItem, MaxKeysPerPartition and TableType are parameters of partition filters,
+	// see cuckoo.NewFilter
+	TagsPerBucket       uint
+	BitsPerItem         uint
+	MaxKeysPerPartition uint
+	TableType           uint
+}
+
+// Cluster is a client of a partitioned filter. It is safe for concurrent use, operations wait while
+// nodes are added or removed. Membership is kept by the Cluster, so a cluster should be managed by one client
+type Cluster struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	ring   *ring
+	nodes  map[string]*rpc.Client
+	owners []string
+}
+
+// New return a cluster without nodes
+func New(cfg Config) (*Cluster, error) {
+	if cfg.Partitions == 0 {
+		return nil, errors.New("num of partitions should be positive")
+	}
+	if cfg.Replicas == 0 {
+		cfg.Replicas = 64
+	}
+	if err := (cuckoo.Config{TagsPerBucket: cfg.TagsPerBucket, BitsPerItem: cfg.BitsPerItem, TableType: cfg.TableType}).Validate(); err != nil {
+		return nil, err
+	}
+	return &Cluster{
+		cfg:    cfg,
+		ring:   newRing(cfg.Replicas),
+		nodes:  make(map[string]*rpc.Client),
+		owners: make([]string, cfg.Partitions),
+	}, nil
+}
+
+func (c *Cluster) partition(key []byte) uint {
+	return uint(metro.Hash64(key, partitionSeed) % uint64(c.cfg.Partitions))
+}
+
+func (c *Cluster) filterName(p uint) string {
+	return c.cfg.Name + "-" + strconv.FormatUint(uint64(p), 10)
+}
+
+// Owner return the node holding key
+func (c *Cluster) Owner(key []byte) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.owners[c.partition(key)]
+}
+
+// Nodes return names of all nodes in order
+func (c *Cluster) Nodes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	nodes := make([]string, 0, len(c.nodes))
+	for node := range c.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// AddNode add a node served by client, and migrate partitions it takes over from other nodes.
+// Partitions are created on the first node
+func (c *Cluster) AddNode(node string, client *rpc.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.nodes[node]; ok {
+		return fmt.Errorf("node %q already exists", node)
+	}
+	c.nodes[node] = client
+	c.ring.add(node)
+	if len(c.nodes) == 1 {
+		for p := range c.owners {
+			if err := c.create(node, uint(p)); err != nil {
+				c.ring.remove(node)
+				delete(c.nodes, node)
+				return err
+			}
+			c.owners[p] = node
+		}
+		return nil
+	}
+	return c.rebalance()
+}
+
+// RemoveNode migrate partitions of a node to the others and remove it
+func (c *Cluster) RemoveNode(node string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.nodes[node]; !ok {
+		return fmt.Errorf("node %q not found", node)
+	}
+	if len(c.nodes) == 1 {
+		return errors.New("can't remove the last node")
+	}
+	c.ring.remove(node)
+	if err := c.rebalance(); err != nil {
+		return err
+	}
+	delete(c.nodes, node)
+	return nil
+}
+
+// Rebalance resume migration stopped by an error of AddNode or RemoveNode
+func (c *Cluster) Rebalance() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rebalance()
+}
+
+// rebalance migrate partitions whose owners change on the ring. Migrated partitions are committed
+// one by one, so the cluster stays consistent if it stops at an error
+func (c *Cluster) rebalance() error {
+	for p, from := range c.owners {
+		to := c.ring.owner(uint(p))
+		if to == from {
+			continue
+		}
+		if err := c.migrate(uint(p), from, to); err != nil {
+			return fmt.Errorf("migrate partition %d from %q to %q: %w", p, from, to, err)
+		}
+		c.owners[p] = to
+	}
+	return nil
+}
+
+// migrate copy partition p from node to another by inserting its fingerprints into a new filter
+func (c *Cluster) migrate(p uint, from, to string) error {
+	name := c.filterName(p)
+	var snapshot []byte
+	if err := c.nodes[from].Call(cuckoorpc.ServiceName+".Snapshot", cuckoorpc.NameArgs{Name: name}, &snapshot); err != nil {
+		return err
+	}
+	// drop leftover of a failed migration
+	_ = c.nodes[to].Call(cuckoorpc.ServiceName+".Drop", cuckoorpc.NameArgs{Name: name}, &struct{}{})
+	if err := c.create(to, p); err != nil {
+		return err
+	}
+	if err := c.nodes[to].Call(cuckoorpc.ServiceName+".Merge", cuckoorpc.DataArgs{Name: name, Data: snapshot}, &struct{}{}); err != nil {
+		return err
+	}
+	return c.nodes[from].Call(cuckoorpc.ServiceName+".Drop", cuckoorpc.NameArgs{Name: name}, &struct{}{})
+}
+
+func (c *Cluster) create(node string, p uint) error {
+	args := cuckoorpc.CreateArgs{
+		Name:          c.filterName(p),
+		TagsPerBucket: c.cfg.TagsPerBucket,
+		BitsPerItem:   c.cfg.BitsPerItem,
+		MaxNumKeys:    c.cfg.MaxKeysPerPartition,
+		TableType:     c.cfg.TableType,
+	}
+	return c.nodes[node].Call(cuckoorpc.ServiceName+".Create", args, &struct{}{})
+}
+
+// keyOp call method of the partition of key
+func (c *Cluster) keyOp(method string, key []byte) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.nodes) == 0 {
+		return false, errors.New("cluster has no nodes")
+	}
+	p := c.partition(key)
+	var ok bool
+	err := c.nodes[c.owners[p]].Call(cuckoorpc.ServiceName+"."+method, cuckoorpc.KeyArgs{Name: c.filterName(p), Key: key}, &ok)
+	return ok, err
+}
+
+// Add add a key, return false when its partition is full
+func (c *Cluster) Add(key []byte) (bool, error) {
+	return c.keyOp("Add", key)
+}
+
+// AddUnique add a key, return false when it is already contained or its partition is full
+func (c *Cluster) AddUnique(key []byte) (bool, error) {
+	return c.keyOp("AddUnique", key)
+}
+
+// Contain return if cluster contains a key
+func (c *Cluster) Contain(key []byte) (bool, error) {
+	return c.keyOp("Contain", key)
+}
+
+// Delete delete a key, return false when it not exist
+func (c *Cluster) Delete(key []byte) (bool, error) {
+	return c.keyOp("Delete", key)
+}
+
+// batchOp group keys by partition and call fn for every partition concurrently,
+// with indexes of its keys in keys
+func (c *Cluster) batchOp(keys [][]byte, fn func(client *rpc.Client, name string, keys [][]byte, indexes []int) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.nodes) == 0 {
+		return errors.New("cluster has no nodes")
+	}
+	indexes := make(map[uint][]int)
+	for i, key := range keys {
+		p := c.partition(key)
+		indexes[p] = append(indexes[p], i)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(indexes))
+	for p, idx := range indexes {
+		part := make([][]byte, len(idx))
+		for j, i := range idx {
+			part[j] = keys[i]
+		}
+		wg.Add(1)
+		go func(p uint, part [][]byte, idx []int) {
+			defer wg.Done()
+			if err := fn(c.nodes[c.owners[p]], c.filterName(p), part, idx); err != nil {
+				errs <- err
+			}
+		}(p, part, idx)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// AddBatch add keys, return num of keys inserted. When some partitions are full,
+// keys of other partitions are still inserted and cuckoo.ErrFilterFull is returned
+func (c *Cluster) AddBatch(keys [][]byte) (int, error) {
+	var mu sync.Mutex
+	var inserted int
+	full := false
+	err := c.batchOp(keys, func(client *rpc.Client, name string, keys [][]byte, _ []int) error {
+		var reply cuckoorpc.BatchReply
+		if err := client.Call(cuckoorpc.ServiceName+".AddBatch", cuckoorpc.KeysArgs{Name: name, Keys: keys}, &reply); err != nil {
+			return err
+		}
+		mu.Lock()
+		inserted += reply.Inserted
+		full = full || reply.Full
+		mu.Unlock()
+		return nil
+	})
+	if err == nil && full {
+		err = cuckoo.ErrFilterFull
+	}
+	return inserted, err
+}
+
+// ContainBatch set out[i] to whether cluster contains keys[i], out must be at least as long as keys
+func (c *Cluster) ContainBatch(keys [][]byte, out []bool) error {
+	_ = out[:len(keys)]
+	return c.batchOp(keys, func(client *rpc.Client, name string, keys [][]byte, indexes []int) error {
+		var reply []bool
+		if err := client.Call(cuckoorpc.ServiceName+".ContainBatch", cuckoorpc.KeysArgs{Name: name, Keys: keys}, &reply); err != nil {
+			return err
+		}
+		// indexes of partitions are disjoint
+		for j, i := range indexes {
+			out[i] = reply[j]
+		}
+		return nil
+	})
+}
+
+// Size return num of keys of all partitions
+func (c *Cluster) Size() (uint, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var total uint
+	for p, node := range c.owners {
+		if node == "" {
+			continue
+		}
+		var n uint
+		if err := c.nodes[node].Call(cuckoorpc.ServiceName+".Size", cuckoorpc.NameArgs{Name: c.filterName(uint(p))}, &n); err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
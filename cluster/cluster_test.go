@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 21:00
+ */
+
+package cluster
+
+import (
+	"net"
+	"net/rpc"
+	"strconv"
+	"testing"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+	"github.com/linvon/cuckoo-filter/cuckoorpc"
+)
+
+// startNode serve a cuckoorpc.Service on a loopback listener and return a client of it
+func startNode(t *testing.T) *rpc.Client {
+	server := rpc.NewServer()
+	if err := cuckoorpc.Register(server, cuckoorpc.NewService()); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	go server.Accept(l)
+	client, err := rpc.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		l.Close()
+	})
+	return client
+}
+
+func TestCluster(t *testing.T) {
+	c, err := New(Config{Name: "users", Partitions: 16, TagsPerBucket: 4, BitsPerItem: 12, MaxKeysPerPartition: 1000, TableType: cuckoo.TableTypeSingle})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if _, err := c.Add([]byte("a")); err == nil {
+		t.Fatalf("Expected error without nodes")
+	}
+	// nodes are named regardless of their random ports, so that placement of partitions is deterministic
+	node1, client1 := "node1", startNode(t)
+	if err := c.AddNode(node1, client1); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var keys [][]byte
+	for i := 0; i < 6000; i++ {
+		keys = append(keys, []byte("key"+strconv.Itoa(i)))
+	}
+	for _, key := range keys[:1000] {
+		if ok, err := c.Add(key); !ok || err != nil {
+			t.Fatalf("Expected add ok, err %v", err)
+		}
+	}
+	if inserted, err := c.AddBatch(keys[1000:]); inserted != 5000 || err != nil {
+		t.Fatalf("Expected 5000 inserted, instead %d, err %v", inserted, err)
+	}
+
+	node2, client2 := "node2", startNode(t)
+	node3, client3 := "node3", startNode(t)
+	if err := c.AddNode(node2, client2); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if err := c.AddNode(node3, client3); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	partitions := make(map[string]int)
+	for _, owner := range c.owners {
+		partitions[owner]++
+	}
+	if len(partitions) != 3 {
+		t.Fatalf("Expected partitions on 3 nodes, instead %v", partitions)
+	}
+	owners := make(map[string]int)
+	for _, key := range keys {
+		owners[c.Owner(key)]++
+	}
+	if len(owners) != 3 {
+		t.Fatalf("Expected keys on 3 nodes, instead %v", owners)
+	}
+
+	out := make([]bool, len(keys))
+	if err := c.ContainBatch(keys, out); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	for i, ok := range out {
+		if !ok {
+			t.Fatalf("Expected contain key %d after migration", i)
+		}
+	}
+	if n, err := c.Size(); n != 6000 || err != nil {
+		t.Fatalf("Expected size 6000, instead %d, err %v", n, err)
+	}
+	if ok, err := c.Delete(keys[0]); !ok || err != nil {
+		t.Fatalf("Expected delete ok, err %v", err)
+	}
+	if ok, _ := c.Contain(keys[0]); ok {
+		t.Fatalf("Expected key deleted")
+	}
+
+	if err := c.RemoveNode(node1); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	for _, key := range keys[1:] {
+		if ok, err := c.Contain(key); !ok || err != nil {
+			t.Fatalf("Expected contain after removing node, err %v", err)
+		}
+	}
+	// migrated partitions are dropped from the old node
+	var size uint
+	if err := client1.Call(cuckoorpc.ServiceName+".Size", cuckoorpc.NameArgs{Name: "users-0"}, &size); err == nil {
+		t.Fatalf("Expected partition dropped from removed node")
+	}
+	if nodes := c.Nodes(); len(nodes) != 2 {
+		t.Fatalf("Unexpected nodes %v", nodes)
+	}
+}
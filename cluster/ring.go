@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 21:00
+ */
+
+package cluster
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/dgryski/go-metro"
+)
+
+// ringSeed is the hash seed of ring points
+const ringSeed = 0x72696e67
+
+// ring is a consistent-hash ring, every node is placed at replicas points,
+// and a partition belongs to the node of the first point at or after its hash
+type ring struct {
+	replicas uint
+	points   []uint64
+	nodes    map[uint64]string
+}
+
+func newRing(replicas uint) *ring {
+	return &ring{replicas: replicas, nodes: make(map[uint64]string)}
+}
+
+func (r *ring) add(node string) {
+	for i := uint(0); i < r.replicas; i++ {
+		h := metro.Hash64Str(node+"#"+strconv.FormatUint(uint64(i), 10), ringSeed)
+		if _, ok := r.nodes[h]; ok {
+			continue
+		}
+		r.nodes[h] = node
+		r.points = append(r.points, h)
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+func (r *ring) remove(node string) {
+	points := r.points[:0]
+	for _, h := range r.points {
+		if r.nodes[h] == node {
+			delete(r.nodes, h)
+		} else {
+			points = append(points, h)
+		}
+	}
+	r.points = points
+}
+
+// owner return node of partition p, the ring must not be empty
+func (r *ring) owner(p uint) string {
+	h := metro.Hash64Str("partition#"+strconv.FormatUint(uint64(p), 10), ringSeed)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.nodes[r.points[i]]
+}
@@ -0,0 +1,377 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 17:00
+ */
+
+// Command cuckoo build and inspect cuckoo filters saved in the Encode format
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+	"github.com/linvon/cuckoo-filter/resp"
+)
+
+const usage = `Usage: cuckoo <command> [flags] [args]
+
+Commands:
+  build   -o FILTER [-b 4] [-f 9] [-t single|packed|vacuum] [-n MAXKEYS] [-compact] KEYFILE
+          build a filter from newline-delimited keys, KEYFILE "-" is stdin
+  query   [-k KEYFILE] FILTER [KEY...]   print whether filter contains keys
+  delete  [-k KEYFILE] [-o OUT] FILTER [KEY...]   delete keys and save filter
+  info    FILTER                          print filter's info
+  stats   FILTER                          print filter's stats in JSON
+  merge   -o OUT FILTER FILTER...         merge filters of the same parameters
+  convert -o OUT -t single|packed|vacuum FILTER  store filter in another table type
+  resp    [-addr :6379] [-f 8]            serve RedisBloom CF.* commands over the Redis protocol
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "cuckoo:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	cmd := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+	cmd.flags = flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.Usage = func() { fmt.Fprint(stderr, usage) }
+
+	switch args[0] {
+	case "build":
+		return cmd.build(args[1:])
+	case "query":
+		return cmd.query(args[1:])
+	case "delete":
+		return cmd.delete(args[1:])
+	case "info":
+		return cmd.info(args[1:])
+	case "stats":
+		return cmd.stats(args[1:])
+	case "merge":
+		return cmd.merge(args[1:])
+	case "convert":
+		return cmd.convert(args[1:])
+	case "resp":
+		return cmd.resp(args[1:])
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return errUsage
+	}
+}
+
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	flags          *flag.FlagSet
+}
+
+// parse parse flags and check num of positional args is at least min
+func (c *command) parse(args []string, min int) error {
+	if err := c.flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if c.flags.NArg() < min {
+		c.flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// usageError print err with usage and return errUsage
+func (c *command) usageError(err error) error {
+	fmt.Fprintf(c.stderr, "%s: %v\n", c.flags.Name(), err)
+	c.flags.Usage()
+	return errUsage
+}
+
+func (c *command) build(args []string) error {
+	out := c.flags.String("o", "", "output filter file")
+	tagsPerBucket := c.flags.Uint("b", 4, "tags per bucket")
+	bitsPerItem := c.flags.Uint("f", 9, "bits per item")
+	tableType := c.flags.String("t", "single", "table type, single, packed or vacuum")
+	maxNumKeys := c.flags.Uint("n", 0, "num of keys the filter plan for, default to num of keys in KEYFILE")
+	compact := c.flags.Bool("compact", false, "build the smallest filter holding the keys with BuildFilter, -n is ignored")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("build: -o is required")
+	}
+	typ, err := parseTableType(*tableType)
+	if err != nil {
+		return err
+	}
+	cfg := cuckoo.Config{TagsPerBucket: *tagsPerBucket, BitsPerItem: *bitsPerItem, TableType: typ}
+	if err := cfg.Validate(); err != nil {
+		return c.usageError(err)
+	}
+	keys, err := c.readKeys(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var cf *cuckoo.Filter
+	if *compact {
+		cf, err = cuckoo.BuildFilter(keys, cfg)
+		if err != nil {
+			return err
+		}
+	} else {
+		n := *maxNumKeys
+		if n == 0 {
+			n = uint(len(keys))
+		}
+		cf = cuckoo.NewFilter(*tagsPerBucket, *bitsPerItem, n, typ)
+		for i, key := range keys {
+			if !cf.Add(key) {
+				return fmt.Errorf("build: filter is full after %d of %d keys", i, len(keys))
+			}
+		}
+	}
+	fmt.Fprintf(c.stdout, "%d keys, load factor %.4f, %d bytes\n", cf.Size(), cf.LoadFactor(), cf.SizeInBytes())
+	return writeFilter(*out, cf)
+}
+
+func (c *command) query(args []string) error {
+	keyFile := c.flags.String("k", "", "file of newline-delimited keys to query")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	keys, err := c.argKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(c.stdout)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%v\n", key, cf.Contain(key))
+	}
+	return w.Flush()
+}
+
+func (c *command) delete(args []string) error {
+	keyFile := c.flags.String("k", "", "file of newline-delimited keys to delete")
+	out := c.flags.String("o", "", "output filter file, default to FILTER")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	keys, err := c.argKeys(*keyFile)
+	if err != nil {
+		return err
+	}
+	var deleted int
+	for _, key := range keys {
+		if cf.Delete(key) {
+			deleted++
+		}
+	}
+	fmt.Fprintf(c.stdout, "%d of %d keys deleted\n", deleted, len(keys))
+	if *out == "" {
+		*out = c.flags.Arg(0)
+	}
+	return writeFilter(*out, cf)
+}
+
+func (c *command) info(args []string) error {
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(c.stdout, cf.Info())
+	return err
+}
+
+func (c *command) stats(args []string) error {
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cf.Stats())
+}
+
+func (c *command) merge(args []string) error {
+	out := c.flags.String("o", "", "output filter file")
+	if err := c.parse(args, 2); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("merge: -o is required")
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, path := range c.flags.Args()[1:] {
+		other, err := readFilter(path)
+		if err != nil {
+			return err
+		}
+		if err := cf.Merge(other); err != nil {
+			return fmt.Errorf("merge %s: %w", path, err)
+		}
+	}
+	fmt.Fprintf(c.stdout, "%d keys, load factor %.4f\n", cf.Size(), cf.LoadFactor())
+	return writeFilter(*out, cf)
+}
+
+func (c *command) convert(args []string) error {
+	out := c.flags.String("o", "", "output filter file")
+	tableType := c.flags.String("t", "", "table type, single, packed or vacuum")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("convert: -o is required")
+	}
+	typ, err := parseTableType(*tableType)
+	if err != nil {
+		return err
+	}
+	cf, err := readFilter(c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	ncf, err := cf.ConvertTo(typ)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "%d bytes to %d bytes\n", cf.SizeInBytes(), ncf.SizeInBytes())
+	return writeFilter(*out, ncf)
+}
+
+func (c *command) resp(args []string) error {
+	addr := c.flags.String("addr", ":6379", "TCP address to listen on")
+	bitsPerItem := c.flags.Uint("f", 8, "bits per item of created filters")
+	if err := c.parse(args, 0); err != nil {
+		return err
+	}
+	// bucket size is given by each CF.RESERVE, so only bits per item is checked here
+	if err := (cuckoo.Config{TagsPerBucket: 1, BitsPerItem: *bitsPerItem, TableType: cuckoo.TableTypeSingle}).Validate(); err != nil {
+		return c.usageError(err)
+	}
+	s := resp.NewServer()
+	s.BitsPerItem = *bitsPerItem
+	return s.ListenAndServe(*addr)
+}
+
+func parseTableType(s string) (uint, error) {
+	switch s {
+	case "single":
+		return cuckoo.TableTypeSingle, nil
+	case "packed":
+		return cuckoo.TableTypePacked, nil
+	case "vacuum":
+		return cuckoo.TableTypeVacuum, nil
+	default:
+		return 0, fmt.Errorf("unknown table type %q, should be single, packed or vacuum", s)
+	}
+}
+
+// argKeys return keys from keyFile if set, or positional args after the filter
+func (c *command) argKeys(keyFile string) ([][]byte, error) {
+	if keyFile != "" {
+		return c.readKeys(keyFile)
+	}
+	var keys [][]byte
+	for _, arg := range c.flags.Args()[1:] {
+		keys = append(keys, []byte(arg))
+	}
+	return keys, nil
+}
+
+// readKeys read non-empty lines of path, "-" is stdin
+func (c *command) readKeys(path string) ([][]byte, error) {
+	r := c.stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	var keys [][]byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			keys = append(keys, append([]byte(nil), line...))
+		}
+	}
+	return keys, scanner.Err()
+}
+
+func readFilter(path string) (*cuckoo.Filter, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cf, err := cuckoo.DecodeFrom(b)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return cf, nil
+}
+
+// writeFilter write cf into a temporary file in the directory of path and rename it to path,
+// so path is either the old or the new filter when writing fails
+func writeFilter(path string, cf *cuckoo.Filter) error {
+	b, err := cf.Encode()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(b); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
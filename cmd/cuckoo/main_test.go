@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 17:00
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCmd(t *testing.T, stdin string, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	if err := run(args, strings.NewReader(stdin), &stdout, &stderr); err != nil {
+		t.Fatalf("%v: err %v, stderr %s", args, err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	var keys1, keys2 strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&keys1, "key-%d\n", i)
+		fmt.Fprintf(&keys2, "other-%d\n", i)
+	}
+	keyFile := filepath.Join(dir, "keys")
+	_ = os.WriteFile(keyFile, []byte(keys1.String()), 0644)
+	f1, f2, merged, packed := filepath.Join(dir, "f1"), filepath.Join(dir, "f2"), filepath.Join(dir, "merged"), filepath.Join(dir, "packed")
+
+	runCmd(t, "", "build", "-o", f1, "-n", "4000", keyFile)
+	runCmd(t, keys2.String(), "build", "-o", f2, "-n", "4000", "-f", "9", "-")
+	if out := runCmd(t, "", "query", f1, "key-1", "missing"); out != "key-1\ttrue\nmissing\tfalse\n" {
+		t.Fatalf("Unexpected query output %q", out)
+	}
+	if out := runCmd(t, "", "query", "-k", keyFile, f1); strings.Count(out, "\ttrue\n") != 1000 {
+		t.Fatalf("Expected all keys contained")
+	}
+
+	runCmd(t, "", "merge", "-o", merged, f1, f2)
+	runCmd(t, "", "convert", "-o", packed, "-t", "packed", merged)
+	if out := runCmd(t, "", "delete", packed, "key-1", "other-1"); out != "2 of 2 keys deleted\n" {
+		t.Fatalf("Unexpected delete output %q", out)
+	}
+	if out := runCmd(t, "", "query", packed, "key-2", "other-2"); out != "key-2\ttrue\nother-2\ttrue\n" {
+		t.Fatalf("Unexpected query output %q", out)
+	}
+	if out := runCmd(t, "", "stats", packed); !strings.Contains(out, `"Items": 1998`) || !strings.Contains(out, `"TableType": 1`) {
+		t.Fatalf("Unexpected stats %s", out)
+	}
+	if out := runCmd(t, "", "info", packed); !strings.Contains(out, "Keys stored: 1998") {
+		t.Fatalf("Unexpected info %s", out)
+	}
+
+	runCmd(t, "", "build", "-compact", "-o", f2, keyFile)
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"merge", "-o", merged, f1, f2}, nil, &stdout, &stderr); err == nil {
+		t.Errorf("Expected error merging filters of different sizes")
+	}
+	if err := run([]string{"frobnicate"}, nil, &stdout, &stderr); !errors.Is(err, errUsage) {
+		t.Errorf("Expected usage error, instead %v", err)
+	}
+	for _, args := range [][]string{
+		{"build", "-b", "0", "-o", f1, keyFile},
+		{"build", "-f", "33", "-o", f1, keyFile},
+		{"build", "-compact", "-b", "0", "-o", f1, keyFile},
+		{"resp", "-f", "0"},
+	} {
+		if err := run(args, nil, &stdout, &stderr); !errors.Is(err, errUsage) {
+			t.Errorf("Expected usage error for %v, instead %v", args, err)
+		}
+	}
+
+	// filter is replaced by rename, and no temporary file is left
+	if out := runCmd(t, "", "delete", f1, "key-3"); out != "1 of 1 keys deleted\n" {
+		t.Fatalf("Unexpected delete output %q", out)
+	}
+	if out := runCmd(t, "", "query", f1, "key-3", "key-4"); out != "key-3\tfalse\nkey-4\ttrue\n" {
+		t.Fatalf("Unexpected query output %q", out)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(matches) != 0 {
+		t.Errorf("Unexpected temporary files %v", matches)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/18 23:10
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// CppHasher follow TwoIndependentMultiplyShift, the default HashFamily of efficient/cuckoofilter as written in
+// its hashutil.h, which hash an uint64 item into (add + multiply * item) >> 64 with 128 bits arithmetic.
+// It is not tested against the C++ library itself, see ImportCpp.
+// C++ filters draw multiply and add from std::random_device, so they must be exported along with the table
+type CppHasher struct {
+	MultiplyHi, MultiplyLo uint64
+	AddHi, AddLo           uint64
+}
+
+// HashUint64 return the hash of item
+func (h *CppHasher) HashUint64(item uint64) uint64 {
+	hi, lo := bits.Mul64(h.MultiplyLo, item)
+	hi += h.MultiplyHi * item
+	_, carry := bits.Add64(lo, h.AddLo, 0)
+	hi, _ = bits.Add64(hi, h.AddHi, carry)
+	return hi
+}
+
+// Hash64 return the hash of item read as a little-endian uint64, which is the memory layout of
+// uint64_t items on the machines C++ filters run on. Shorter items are zero padded, and longer ones
+// are rejected with ErrItemTooLong since C++ filters only hash uint64_t items
+func (h *CppHasher) Hash64(item []byte) (uint64, error) {
+	if len(item) > bytesPerUint64 {
+		return 0, ErrItemTooLong
+	}
+	var b [bytesPerUint64]byte
+	copy(b[:], item)
+	return h.HashUint64(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+// efficient/cuckoofilter mask hash into tag, and use 1 instead of the empty tag
+func cppTagHash(hv uint32, bitsPerItem uint) uint32 {
+	tag := hv & (1<<bitsPerItem - 1)
+	if tag == 0 {
+		tag = 1
+	}
+	return tag
+}
+
+// NewCppFilter return a new filter which is sized, hashed and laid out following the sources of
+// CuckooFilter<uint64_t, bitsPerItem, SingleTable> of efficient/cuckoofilter, see ImportCpp.
+// Items longer than 8 bytes are rejected: Add, Contain and Delete return false for them, and AddBatch
+// return ErrItemTooLong. PackedTable is not supported
+func NewCppFilter(bitsPerItem, maxNumKeys uint, hasher CppHasher) *Filter {
+	// C++ filters always use 4 tags per bucket
+	f := NewFilter(4, bitsPerItem, maxNumKeys, TableTypeSingle)
+	f.cpp = &hasher
+	return f
+}
+
+// CppTable describe the state of a C++ CuckooFilter<uint64_t, BitsPerItem, SingleTable>
+type CppTable struct {
+	// TableType is TableTypeSingle for SingleTable, PackedTable is not supported
+	TableType   uint
+	BitsPerItem uint
+	NumBuckets  uint
+	// Buckets is the memory of table's buckets_, trailing padding may be omitted
+	Buckets []byte
+
+	NumItems    uint
+	VictimUsed  bool
+	VictimIndex uint
+	VictimTag   uint32
+}
+
+// ImportCpp return a filter using a copy of the table dumped from efficient/cuckoofilter,
+// hasher must hold the factors of the C++ filter's TwoIndependentMultiplyShift.
+// The layout is written after the sources of the C++ library, and only tested against
+// testdata/cpp_golden.cc, a reimplementation of them, not against tables of the library itself
+func ImportCpp(t CppTable, hasher CppHasher) (*Filter, error) {
+	if t.NumBuckets == 0 || t.NumBuckets&(t.NumBuckets-1) != 0 {
+		return nil, fmt.Errorf("num of buckets %d is not a power of two", t.NumBuckets)
+	}
+	var expectedLength, tableLength uint
+	switch t.TableType {
+	case TableTypeSingle:
+		switch t.BitsPerItem {
+		case 2, 4, 8, 12, 16, 32:
+		default:
+			return nil, fmt.Errorf("SingleTable does not support %d bits per item", t.BitsPerItem)
+		}
+		expectedLength = t.BitsPerItem * 4 * t.NumBuckets / bitsPerByte
+		tableLength = expectedLength
+	case TableTypePacked:
+		return nil, fmt.Errorf("import of PackedTable is not supported")
+	default:
+		return nil, fmt.Errorf("unknown table type %d", t.TableType)
+	}
+	if uint(len(t.Buckets)) < expectedLength {
+		return nil, fmt.Errorf("buckets length should be at least %d but got %d", expectedLength, len(t.Buckets))
+	}
+	if t.VictimUsed && t.VictimIndex >= t.NumBuckets {
+		return nil, fmt.Errorf("victim index %d out of range", t.VictimIndex)
+	}
+
+	buckets := make([]byte, tableLength)
+	copy(buckets, t.Buckets[:expectedLength])
+	table := getTable(t.TableType).(table)
+	if err := table.Init(4, t.BitsPerItem, t.NumBuckets, buckets); err != nil {
+		return nil, err
+	}
+	return &Filter{
+		table:    table,
+		numItems: t.NumItems,
+		victim: victimCache{
+			index: t.VictimIndex,
+			tag:   t.VictimTag,
+			used:  t.VictimUsed,
+		},
+		cpp: &hasher,
+	}, nil
+}
+
+// DecodeCpp returns a Cuckoo Filter using a copy of the provided byte slice encoded from a filter returned by
+// NewCppFilter or ImportCpp, Encode doesn't record the hasher so it must be provided again
+func DecodeCpp(b []byte, hasher CppHasher) (*Filter, error) {
+	f, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	f.cpp = &hasher
+	return f, nil
+}
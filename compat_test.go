@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/18 23:10
+ */
+
+package cuckoo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// cppKey is the key sequence inserted by testdata/cpp_golden.cc. The golden tables come from a reimplementation
+// of SingleTable of efficient/cuckoofilter instead of the library itself, see the program
+func cppKey(i uint64) []byte {
+	b := make([]byte, bytesPerUint64)
+	binary.LittleEndian.PutUint64(b, i*0x9e3779b97f4a7c15+1)
+	return b
+}
+
+func parseUint(t *testing.T, s string, base int) uint64 {
+	n, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	return n
+}
+
+func TestImportCpp(t *testing.T) {
+	file, err := os.Open("testdata/cpp_singletable.golden")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	defer file.Close()
+
+	var hasher CppHasher
+	var cppTable CppTable
+	var hashes [][]string
+	var tables int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch fields[0] {
+		case "multiply":
+			hasher.MultiplyHi, hasher.MultiplyLo = parseUint(t, fields[1][:16], 16), parseUint(t, fields[1][16:], 16)
+		case "add":
+			hasher.AddHi, hasher.AddLo = parseUint(t, fields[1][:16], 16), parseUint(t, fields[1][16:], 16)
+		case "filter":
+			cppTable = CppTable{
+				TableType:   TableTypeSingle,
+				BitsPerItem: uint(parseUint(t, fields[1], 10)),
+				NumBuckets:  uint(parseUint(t, fields[2], 10)),
+				NumItems:    uint(parseUint(t, fields[4], 10)),
+				VictimUsed:  fields[5] == "1",
+				VictimIndex: uint(parseUint(t, fields[6], 10)),
+				VictimTag:   uint32(parseUint(t, fields[7], 10)),
+			}
+			hashes = hashes[:0]
+		case "hash":
+			hashes = append(hashes, fields[1:])
+		case "table":
+			tables++
+			cppTable.Buckets, err = hex.DecodeString(fields[1])
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+			cf, err := ImportCpp(cppTable, hasher)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+			for _, h := range hashes {
+				key := make([]byte, bytesPerUint64)
+				binary.LittleEndian.PutUint64(key, parseUint(t, h[0], 10))
+				if hash, err := hasher.Hash64(key); err != nil || hash != parseUint(t, h[1], 10) {
+					t.Fatalf("Expected hash %s, instead %d, err %v", h[1], hash, err)
+				}
+				index, tag := cf.generateIndexTagHash(key)
+				if index != uint(parseUint(t, h[2], 10)) || tag != uint32(parseUint(t, h[3], 10)) {
+					t.Fatalf("Expected index %s tag %s, instead index %d tag %d, bits %d", h[2], h[3], index, tag, cppTable.BitsPerItem)
+				}
+			}
+
+			if cf.Size() != cppTable.NumItems {
+				t.Fatalf("Expected size %d, instead %d", cppTable.NumItems, cf.Size())
+			}
+			encodedBytes, _ := cf.Encode()
+			ncf, err := DecodeCpp(encodedBytes, hasher)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+			for i := uint64(0); i < uint64(cppTable.NumItems); i++ {
+				if !ncf.Contain(cppKey(i)) {
+					t.Fatalf("Expected contain key %d, bits %d", i, cppTable.BitsPerItem)
+				}
+			}
+			for i := uint64(0); i < uint64(cppTable.NumItems); i++ {
+				if !ncf.Delete(cppKey(i)) {
+					t.Fatalf("Expected delete key %d, bits %d", i, cppTable.BitsPerItem)
+				}
+			}
+			if ncf.Size() != 0 {
+				t.Fatalf("Expected empty filter, instead size %d", ncf.Size())
+			}
+		}
+	}
+	if tables == 0 {
+		t.Fatalf("Expected golden tables")
+	}
+
+	if _, err := ImportCpp(CppTable{TableType: TableTypeSingle, BitsPerItem: 9, NumBuckets: 64}, hasher); err == nil {
+		t.Errorf("Expected error for bits unsupported by C++ SingleTable")
+	}
+	if _, err := ImportCpp(CppTable{TableType: TableTypeSingle, BitsPerItem: 8, NumBuckets: 64, Buckets: make([]byte, 10)}, hasher); err == nil {
+		t.Errorf("Expected error for short buckets")
+	}
+
+	if _, err := ImportCpp(CppTable{TableType: TableTypePacked, BitsPerItem: 13, NumBuckets: 64, Buckets: make([]byte, 1024)}, hasher); err == nil {
+		t.Errorf("Expected error for PackedTable")
+	}
+
+	cf := NewCppFilter(12, 1000, hasher)
+	for i := uint64(0); i < 900; i++ {
+		cf.Add(cppKey(i))
+	}
+	for i := uint64(0); i < 900; i++ {
+		if !cf.Contain(cppKey(i)) {
+			t.Fatalf("Expected contain key %d", i)
+		}
+	}
+
+	// C++ filters only hash uint64_t items, longer items are rejected instead of truncated
+	long := append(cppKey(0), 'x')
+	if _, err := hasher.Hash64(long); err != ErrItemTooLong {
+		t.Errorf("Expected ErrItemTooLong, instead %v", err)
+	}
+	if cf.Add(long) || cf.Contain(long) || cf.Delete(long) || cf.AddString(string(long)) || cf.ContainString(string(long)) {
+		t.Errorf("Expected items longer than 8 bytes rejected")
+	}
+	if _, err := cf.AddBatch([][]byte{cppKey(1000), long}); err != ErrItemTooLong || cf.Contain(cppKey(1000)) {
+		t.Errorf("Expected batch with long item rejected, err %v", err)
+	}
+	out := make([]bool, 2)
+	if cf.ContainBatch([][]byte{cppKey(0), long}, out); !out[0] || out[1] {
+		t.Errorf("Expected batch lookup of long item false, instead %v", out)
+	}
+}
@@ -11,6 +11,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"github.com/dgryski/go-metro"
 )
@@ -23,11 +28,17 @@ const (
 	TableTypeSingle = 0
 	// TableTypePacked packed table, use semi-sort to save 1 bit per item
 	TableTypePacked = 1
+	// TableTypeVacuum single table of any num of buckets, use chunked alternate ranges of vacuum filter
+	TableTypeVacuum = 2
 )
 
 type table interface {
 	Init(tagsPerBucket, bitsPerTag, num uint, initialBucketsHint []byte) error
+	TableType() uint
 	NumBuckets() uint
+	TagsPerBucket() uint
+	ReadTagsFromBucket(i uint, tags []uint32)
+	FindTagInBucket(i uint, tag uint32) bool
 	FindTagInBuckets(i1, i2 uint, tag uint32) bool
 	DeleteTagFromBucket(i uint, tag uint32) bool
 	InsertTagToBucket(i uint, tag uint32, kickOut bool, oldTag *uint32) bool
@@ -38,17 +49,35 @@ type table interface {
 	Reader() (io.Reader, uint)
 	Decode([]byte) error
 	Reset()
+	EnableDirtyTracking(bucketsPerPage uint)
+	DisableDirtyTracking()
+	RangeDirty(fn func(offset, length uint) bool)
+	ClearDirty()
+	clone() table
+	// touchBucket read a byte of bucket i to bring it into cache
+	touchBucket(i uint) byte
 }
 
 func getTable(tableType uint) interface{} {
 	switch tableType {
 	case TableTypePacked:
 		return NewPackedTable()
+	case TableTypeVacuum:
+		return NewVacuumTable()
 	default:
 		return NewSingleTable()
 	}
 }
 
+var (
+	// ErrFilterFull is returned when filter has no room for more items
+	ErrFilterFull = errors.New("cuckoo filter is full")
+	// ErrIncompatible is returned when filters differ in table type, size, fingerprint or hasher
+	ErrIncompatible = errors.New("cuckoo filters are incompatible")
+	// ErrItemTooLong is returned for items longer than 8 bytes of filters hashed by CppHasher
+	ErrItemTooLong = errors.New("cuckoo item longer than 8 bytes")
+)
+
 type victimCache struct {
 	index uint
 	tag   uint32
@@ -62,6 +91,50 @@ type Filter struct {
 	victim   victimCache
 	numItems uint
 	table    table
+	// vacuum is table when it is a VacuumTable, resolved once so indexing needs no type assertion
+	vacuum *VacuumTable
+	// cpp is set when filter derive index and tag like efficient/cuckoofilter, see CppHasher
+	cpp *CppHasher
+	// oldTag receive tag kicked out by table in addImpl, a local variable would be allocated per insertion
+	oldTag   uint32
+	onInsert func(kicks int, ok bool)
+	// counters is nil until EnableCounters is called
+	counters *counters
+}
+
+// counters are cumulative num of operations since EnableCounters is called, see Stats
+type counters struct {
+	// lookups and lookupHits are updated atomically, so lookups stay read-only for concurrent readers.
+	// They are the first fields to be 64 bits aligned on 32 bits platforms
+	lookups       uint64
+	lookupHits    uint64
+	inserts       uint64
+	failedInserts uint64
+	kicks         uint64
+	deletes       uint64
+	// kickHistogram[k] is num of insertions moving tags in [2^(k-1), 2^k) times, k > 0
+	kickHistogram [kickHistogramSize]uint64
+}
+
+// kickHistogramSize is enough for kMaxCuckooCount kicks
+const kickHistogramSize = 10
+
+// EnableCounters start counting operations reported by Stats, counters are zero before it is called.
+// They are not encoded, and a decoded or converted filter starts without them
+func (f *Filter) EnableCounters() {
+	if f.counters == nil {
+		f.counters = &counters{}
+	}
+}
+
+func (f *Filter) recordKicks(count uint) {
+	if f.counters != nil {
+		f.counters.kicks += uint64(count)
+		f.counters.kickHistogram[bits.Len(count)]++
+	}
+	if f.onInsert != nil {
+		f.onInsert(int(count), true)
+	}
 }
 
 //NewFilter return a new initialized filter
@@ -69,9 +142,31 @@ type Filter struct {
 	tagsPerBucket: num of tags for each bucket, which is b in paper. tag is fingerprint, which is f in paper.
 	bitPerItem: num of bits for each item, which is length of tag(fingerprint)
 	maxNumKeys: num of keys that filter will store. this value should close to and lower
-				nextPow2(maxNumKeys/tagsPerBucket) * maxLoadFactor. cause table.NumBuckets is always a power of two
+				nextPow2(maxNumKeys/tagsPerBucket) * maxLoadFactor. cause table.NumBuckets is always a power of two,
+				except TableTypeVacuum, whose num of buckets is close to maxNumKeys/tagsPerBucket/maxLoadFactor
 */
 func NewFilter(tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) *Filter {
+	numBuckets := numBucketsFor(tagsPerBucket, maxNumKeys)
+	if tableType == TableTypeVacuum {
+		load := maxLoadFactor(tagsPerBucket) * vacuumLoadMargin
+		numBuckets = vacuumNumBuckets(uint(math.Ceil(float64(maxNumKeys)/float64(tagsPerBucket)/load)), tagsPerBucket)
+	}
+	table := getTable(tableType).(table)
+	_ = table.Init(tagsPerBucket, bitsPerItem, numBuckets, nil)
+	return &Filter{
+		table:  table,
+		vacuum: asVacuum(table),
+	}
+}
+
+// asVacuum return t as a VacuumTable, or nil when it is not
+func asVacuum(t table) *VacuumTable {
+	vt, _ := t.(*VacuumTable)
+	return vt
+}
+
+// numBucketsFor return the power of two num of buckets holding maxNumKeys under max load factor
+func numBucketsFor(tagsPerBucket, maxNumKeys uint) uint {
 	numBuckets := getNextPow2(uint64(maxNumKeys / tagsPerBucket))
 	if float64(maxNumKeys)/float64(numBuckets*tagsPerBucket) > maxLoadFactor(tagsPerBucket) {
 		numBuckets <<= 1
@@ -79,34 +174,84 @@ func NewFilter(tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) *Filter {
 	if numBuckets == 0 {
 		numBuckets = 1
 	}
-	table := getTable(tableType).(table)
-	_ = table.Init(tagsPerBucket, bitsPerItem, numBuckets, nil)
-	return &Filter{
-		table: table,
-	}
+	return numBuckets
 }
 
 func (f *Filter) indexHash(hv uint32) uint {
+	if f.vacuum != nil {
+		return f.vacuum.indexHash(hv)
+	}
 	// table.NumBuckets is always a power of two, so modulo can be replaced with bitwise-and:
 	return uint(hv) & (f.table.NumBuckets() - 1)
 }
 
 func (f *Filter) tagHash(hv uint32) uint32 {
+	if f.cpp != nil {
+		return cppTagHash(hv, f.table.BitsPerItem())
+	}
 	return hv%((1<<f.table.BitsPerItem())-1) + 1
 }
 
-func (f *Filter) generateIndexTagHash(item []byte) (index uint, tag uint32) {
-	hash := metro.Hash64(item, 1337)
+// hash return hash of item, items rejected by tooLong must not be hashed
+func (f *Filter) hash(item []byte) uint64 {
+	if f.cpp != nil {
+		var b [bytesPerUint64]byte
+		copy(b[:], item)
+		return f.cpp.HashUint64(binary.LittleEndian.Uint64(b[:]))
+	}
+	return metro.Hash64(item, 1337)
+}
+
+func (f *Filter) hashString(item string) uint64 {
+	if f.cpp != nil {
+		// copy the string like hash without converting it to bytes, which may allocate
+		var b [bytesPerUint64]byte
+		copy(b[:], item)
+		return f.cpp.HashUint64(binary.LittleEndian.Uint64(b[:]))
+	}
+	return metro.Hash64Str(item, 1337)
+}
+
+// tooLong return if an item of n bytes can't be hashed, which is longer than 8 bytes for CppHasher
+func (f *Filter) tooLong(n int) bool {
+	return f.cpp != nil && n > bytesPerUint64
+}
+
+func (f *Filter) hashUint64(item uint64) uint64 {
+	if f.cpp != nil {
+		return f.cpp.HashUint64(item)
+	}
+	var b [bytesPerUint64]byte
+	binary.LittleEndian.PutUint64(b[:], item)
+	return metro.Hash64(b[:], 1337)
+}
+
+func (f *Filter) indexTagHash(hash uint64) (index uint, tag uint32) {
 	index = f.indexHash(uint32(hash >> 32))
 	tag = f.tagHash(uint32(hash))
 	return
 }
 
+func (f *Filter) generateIndexTagHash(item []byte) (index uint, tag uint32) {
+	return f.indexTagHash(f.hash(item))
+}
+
 func (f *Filter) altIndex(index uint, tag uint32) uint {
+	if f.vacuum != nil {
+		return f.vacuum.altIndex(index, tag)
+	}
 	// 0x5bd1e995 is the hash constant from MurmurHash2
 	return f.indexHash(uint32(index) ^ (tag * 0x5bd1e995))
 }
 
+// StashSize return num of items kept out of table, which is 1 when the victim slot is used
+func (f *Filter) StashSize() uint {
+	if f.victim.used {
+		return 1
+	}
+	return 0
+}
+
 // Size return num of items that filter store
 func (f *Filter) Size() uint {
 	var c uint
@@ -133,11 +278,10 @@ func (f *Filter) BitsPerItem() float64 {
 
 // Add add an item into filter, return false when filter is full
 func (f *Filter) Add(item []byte) bool {
-	if f.victim.used {
+	if f.tooLong(len(item)) {
 		return false
 	}
-	i, tag := f.generateIndexTagHash(item)
-	return f.addImpl(i, tag)
+	return f.insert(f.generateIndexTagHash(item))
 }
 
 // AddUnique add an item into filter, return false when filter already contains it or filter is full
@@ -148,55 +292,332 @@ func (f *Filter) AddUnique(item []byte) bool {
 	return f.Add(item)
 }
 
+// insert add tag at bucket pair of index i, return false when filter is full
+func (f *Filter) insert(i uint, tag uint32) bool {
+	if f.victim.used {
+		f.rejectInsert()
+		return false
+	}
+	if f.counters != nil {
+		f.counters.inserts++
+	}
+	return f.addImpl(i, tag)
+}
+
+func (f *Filter) rejectInsert() {
+	if f.counters != nil {
+		f.counters.failedInserts++
+	}
+	if f.onInsert != nil {
+		f.onInsert(0, false)
+	}
+}
+
 func (f *Filter) addImpl(i uint, tag uint32) bool {
 	curIndex := i
 	curTag := tag
-	var oldTag uint32
 
 	var count uint
 	var kickOut bool
 	for count = 0; count < kMaxCuckooCount; count++ {
 		kickOut = count > 0
-		oldTag = 0
-		if f.table.InsertTagToBucket(curIndex, curTag, kickOut, &oldTag) {
+		f.oldTag = 0
+		if f.table.InsertTagToBucket(curIndex, curTag, kickOut, &f.oldTag) {
 			f.numItems++
+			f.recordKicks(count)
 			return true
 		}
 		if kickOut {
-			curTag = oldTag
+			curTag = f.oldTag
 		}
 		curIndex = f.altIndex(curIndex, curTag)
 	}
 
+	f.oldTag = 0
+	f.recordKicks(count)
 	f.victim.index = curIndex
 	f.victim.tag = curTag
 	f.victim.used = true
 	return true
 }
 
-// Contain return if filter contains an item
+// AddBatch add items into filter, items are hashed first and inserted in order of bucket index to improve
+// cache locality on large tables. It returns num of items inserted, and ErrFilterFull when filter becomes full,
+// in which case the inserted items are not necessarily a prefix of items. ErrItemTooLong is returned
+// without inserting any item when an item can't be hashed by CppHasher
+func (f *Filter) AddBatch(items [][]byte) (inserted int, err error) {
+	if f.cpp != nil {
+		for _, item := range items {
+			if f.tooLong(len(item)) {
+				return 0, ErrItemTooLong
+			}
+		}
+	}
+	f.rangeBatch(items, func(chunk []batchItem) bool {
+		for _, p := range chunk {
+			if !f.insert(uint(p.index), p.tag) {
+				err = ErrFilterFull
+				return false
+			}
+			inserted++
+		}
+		return true
+	})
+	return inserted, err
+}
+
+// ContainBatch set out[i] to whether filter contains items[i]. Items are looked up block by block, buckets of
+// a block are touched before probed, so that cache misses of the block overlap on large tables rather than
+// stall one by one. out must be at least as long as items
+func (f *Filter) ContainBatch(items [][]byte, out []bool) {
+	_ = out[:len(items)]
+	var block [containBlockSize]batchItem
+	var sink byte
+	for start := 0; start < len(items); start += containBlockSize {
+		end := start + containBlockSize
+		if end > len(items) {
+			end = len(items)
+		}
+		chunk := block[:0]
+		for i, item := range items[start:end] {
+			if f.tooLong(len(item)) {
+				out[start+i] = false
+				continue
+			}
+			index, tag := f.generateIndexTagHash(item)
+			chunk = append(chunk, batchItem{index: uint32(index), tag: tag, pos: uint32(start + i)})
+		}
+		for _, p := range chunk {
+			sink += f.table.touchBucket(uint(p.index))
+		}
+		misses := chunk[:0]
+		for _, p := range chunk {
+			i1 := uint(p.index)
+			if f.table.FindTagInBucket(i1, p.tag) {
+				out[p.pos] = true
+				continue
+			}
+			i2 := f.altIndex(i1, p.tag)
+			if f.victim.used && p.tag == f.victim.tag && (i1 == f.victim.index || i2 == f.victim.index) {
+				out[p.pos] = true
+				continue
+			}
+			misses = append(misses, batchItem{index: uint32(i2), tag: p.tag, pos: p.pos})
+		}
+		for _, p := range misses {
+			sink += f.table.touchBucket(uint(p.index))
+		}
+		hits := uint64(len(chunk) - len(misses))
+		for _, p := range misses {
+			out[p.pos] = f.table.FindTagInBucket(uint(p.index), p.tag)
+			if out[p.pos] {
+				hits++
+			}
+		}
+		if f.counters != nil {
+			atomic.AddUint64(&f.counters.lookups, uint64(len(chunk)))
+			atomic.AddUint64(&f.counters.lookupHits, hits)
+		}
+	}
+	// keep touches from being optimized out
+	runtime.KeepAlive(sink)
+}
+
+const (
+	// containBlockSize is the num of items looked up at a time by ContainBatch, small enough to keep the
+	// touched buckets in cache until they are probed
+	containBlockSize = 256
+)
+
+// batchItem is the index and tag of the pos-th item in a batch, kept small to sort fast
+type batchItem struct {
+	index uint32
+	tag   uint32
+	pos   uint32
+}
+
+const (
+	// batchChunkSize is the least num of items hashed and sorted at a time
+	batchChunkSize = 1 << 16
+	// batchMaxChunkSize bound memory of large batches, which is 12MB a buffer
+	batchMaxChunkSize = 1 << 20
+	// a chunk has an item every batchBucketsPerItem buckets at least, so that sorted lookups are dense enough
+	// to share cache lines and be prefetched on large tables
+	batchBucketsPerItem = 16
+)
+
+// rangeBatch hash items chunk by chunk, and call fn with each chunk grouped by index until fn return false
+func (f *Filter) rangeBatch(items [][]byte, fn func(chunk []batchItem) bool) {
+	size := int(f.table.NumBuckets() / batchBucketsPerItem)
+	if size < batchChunkSize {
+		size = batchChunkSize
+	} else if size > batchMaxChunkSize {
+		size = batchMaxChunkSize
+	}
+	n := len(items)
+	if n > size {
+		n = size
+	}
+	buf := getBatchBuffer(n)
+	defer batchPool.Put(buf)
+	chunk, tmp := buf.chunk[:n], buf.tmp[:n]
+	for start := 0; start < len(items); start += size {
+		end := start + size
+		if end > len(items) {
+			end = len(items)
+		}
+		chunk = chunk[:end-start]
+		for i, item := range items[start:end] {
+			index, tag := f.generateIndexTagHash(item)
+			chunk[i] = batchItem{index: uint32(index), tag: tag, pos: uint32(start + i)}
+		}
+		if !fn(f.groupBatch(chunk, tmp[:len(chunk)])) {
+			return
+		}
+	}
+}
+
+// batchBuffer is the buffers of a chunk and its sorting
+type batchBuffer struct {
+	chunk []batchItem
+	tmp   []batchItem
+}
+
+// batchPool reuse buffers of batches, which are up to 24MB
+var batchPool sync.Pool
+
+// getBatchBuffer get buffers of at least n items from pool
+func getBatchBuffer(n int) *batchBuffer {
+	buf, _ := batchPool.Get().(*batchBuffer)
+	if buf == nil || cap(buf.chunk) < n {
+		buf = &batchBuffer{chunk: make([]batchItem, n), tmp: make([]batchItem, n)}
+	}
+	return buf
+}
+
+const (
+	radixBits = 11
+	radixMask = 1<<radixBits - 1
+)
+
+// radixSortBatch sort batch by index with LSD radix sort, which is faster than comparison sort on large batches
+// tmp is a buffer as long as batch, the sorted result is either batch or tmp
+func radixSortBatch(batch, tmp []batchItem, numBuckets uint) []batchItem {
+	return radixSortBatchFrom(batch, tmp, numBuckets, 0)
+}
+
+// cacheLineSize is the common size of cpu cache lines in bytes
+const cacheLineSize = 64
+
+// groupBatch sort batch by index except the low bits addressing buckets in the same cache line,
+// which need no order, so that large tables are swept with less passes
+func (f *Filter) groupBatch(batch, tmp []batchItem) []batchItem {
+	numBuckets := f.table.NumBuckets()
+	bucketsPerLine := cacheLineSize * numBuckets / f.table.SizeInBytes()
+	var shift uint
+	for 2<<shift <= bucketsPerLine {
+		shift++
+	}
+	return radixSortBatchFrom(batch, tmp, numBuckets, shift)
+}
+
+// radixSortBatchFrom sort batch by bits of index from shift, see radixSortBatch
+func radixSortBatchFrom(batch, tmp []batchItem, numBuckets, shift uint) []batchItem {
+	for ; (numBuckets-1)>>shift > 0; shift += radixBits {
+		var count [radixMask + 2]int
+		for i := range batch {
+			count[(batch[i].index>>shift)&radixMask+1]++
+		}
+		for i := 1; i < len(count); i++ {
+			count[i] += count[i-1]
+		}
+		for i := range batch {
+			d := (batch[i].index >> shift) & radixMask
+			tmp[count[d]] = batch[i]
+			count[d]++
+		}
+		batch, tmp = tmp, batch
+	}
+	return batch
+}
+
+// Contain return if filter contains an item. It only updates lookup counters atomically,
+// so it can be called concurrently as long as no goroutine modifies filter
 func (f *Filter) Contain(key []byte) bool {
+	if f.tooLong(len(key)) {
+		return false
+	}
 	i1, tag := f.generateIndexTagHash(key)
+	return f.containImpl(i1, tag)
+}
+
+func (f *Filter) containImpl(i1 uint, tag uint32) bool {
 	i2 := f.altIndex(i1, tag)
 
-	hit := f.victim.used && tag == f.victim.tag && (i1 == f.victim.index || i2 == f.victim.index)
+	hit := f.victim.used && tag == f.victim.tag && (i1 == f.victim.index || i2 == f.victim.index) ||
+		f.table.FindTagInBuckets(i1, i2, tag)
+	if f.counters != nil {
+		atomic.AddUint64(&f.counters.lookups, 1)
+		if hit {
+			atomic.AddUint64(&f.counters.lookupHits, 1)
+		}
+	}
+	return hit
+}
+
+func (f *Filter) countDelete() {
+	if f.counters != nil {
+		f.counters.deletes++
+	}
+}
 
-	if hit || f.table.FindTagInBuckets(i1, i2, tag) {
-		return true
+// Count return num of times an item may have been added, that is num of its fingerprints in its bucket pair,
+// which can be higher than the real one due to false positives
+func (f *Filter) Count(key []byte) uint {
+	if f.tooLong(len(key)) {
+		return 0
+	}
+	i1, tag := f.generateIndexTagHash(key)
+	i2 := f.altIndex(i1, tag)
+
+	var c uint
+	if f.victim.used && tag == f.victim.tag && (i1 == f.victim.index || i2 == f.victim.index) {
+		c++
 	}
-	return false
+	tags := make([]uint32, f.table.TagsPerBucket())
+	for _, i := range []uint{i1, i2} {
+		f.table.ReadTagsFromBucket(i, tags)
+		for _, t := range tags {
+			if t == tag {
+				c++
+			}
+		}
+		if i1 == i2 {
+			break
+		}
+	}
+	return c
 }
 
 // Delete delete item from filter, return false when item not exist
 func (f *Filter) Delete(key []byte) bool {
+	if f.tooLong(len(key)) {
+		return false
+	}
 	i1, tag := f.generateIndexTagHash(key)
+	return f.deleteImpl(i1, tag)
+}
+
+func (f *Filter) deleteImpl(i1 uint, tag uint32) bool {
 	i2 := f.altIndex(i1, tag)
 
 	if f.table.DeleteTagFromBucket(i1, tag) || f.table.DeleteTagFromBucket(i2, tag) {
 		f.numItems--
+		f.countDelete()
 		goto TryEliminateVictim
 	} else if f.victim.used && tag == f.victim.tag && (i1 == f.victim.index || i2 == f.victim.index) {
 		f.victim.used = false
+		f.countDelete()
 		return true
 	} else {
 		return false
@@ -212,6 +633,201 @@ TryEliminateVictim:
 	return true
 }
 
+// Merge insert all items of other into f, every fingerprint is inserted at its existing bucket pair,
+// so both filters must share table type, num of buckets, tags per bucket, bits per item and hasher.
+// f is left unchanged when an error is returned
+func (f *Filter) Merge(other *Filter) error {
+	if err := f.compatible(other); err != nil {
+		return err
+	}
+	if f.Size()+other.Size() > f.table.SizeInTags()+1 {
+		return ErrFilterFull
+	}
+
+	merged := f.Clone()
+	if merged.victim.used {
+		// give the victim another chance before it blocks further insertion
+		merged.victim.used = false
+		merged.addImpl(merged.victim.index, merged.victim.tag)
+	}
+	full := false
+	other.Range(func(bucket, _ uint, tag uint32) bool {
+		if !merged.insert(bucket, tag) {
+			full = true
+			return false
+		}
+		return true
+	})
+	if full {
+		return ErrFilterFull
+	}
+	*f = *merged
+	return nil
+}
+
+// ConvertTo return a copy of f stored in a table of tableType. The table is rebuilt from stored fingerprints,
+// which keep their bucket index, so original keys are not required.
+// Parameters are checked by Config.Validate, so TableTypePacked requires 4 tags per bucket and at least 4 bits per item.
+// TableTypeVacuum derive bucket index differently, so it can't be converted from or to other types
+func (f *Filter) ConvertTo(tableType uint) (*Filter, error) {
+	tagsPerBucket, bitsPerItem := f.table.TagsPerBucket(), f.table.BitsPerItem()
+	if err := (Config{TagsPerBucket: tagsPerBucket, BitsPerItem: bitsPerItem, TableType: tableType}).Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIncompatible, err)
+	}
+	if (tableType == TableTypeVacuum) != (f.table.TableType() == TableTypeVacuum) {
+		return nil, fmt.Errorf("%w: can't convert table type %d to %d", ErrIncompatible, f.table.TableType(), tableType)
+	}
+
+	table := getTable(tableType).(table)
+	if err := table.Init(tagsPerBucket, bitsPerItem, f.table.NumBuckets(), nil); err != nil {
+		return nil, err
+	}
+	f.Range(func(bucket, slot uint, tag uint32) bool {
+		if slot < tagsPerBucket {
+			table.InsertTagToBucket(bucket, tag, false, nil)
+		}
+		return true
+	})
+	return &Filter{
+		table:    table,
+		vacuum:   asVacuum(table),
+		numItems: f.numItems,
+		victim:   f.victim,
+		cpp:      f.cpp,
+	}, nil
+}
+
+// Range call fn with bucket, slot and tag of every non-empty slot, the victim is reported last
+// with slot equal to num of tags per bucket. Iteration stops when fn return false
+func (f *Filter) Range(fn func(bucket, slot uint, tag uint32) bool) {
+	tags := make([]uint32, f.table.TagsPerBucket())
+	for i := uint(0); i < f.table.NumBuckets(); i++ {
+		f.table.ReadTagsFromBucket(i, tags)
+		for j, tag := range tags {
+			if tag != 0 && !fn(i, uint(j), tag) {
+				return
+			}
+		}
+	}
+	if f.victim.used {
+		fn(f.victim.index, uint(len(tags)), f.victim.tag)
+	}
+}
+
+func (f *Filter) compatible(other *Filter) error {
+	t1, t2 := f.table, other.table
+	switch {
+	case t1.TableType() != t2.TableType():
+		return fmt.Errorf("%w: table type %d and %d", ErrIncompatible, t1.TableType(), t2.TableType())
+	case t1.NumBuckets() != t2.NumBuckets():
+		return fmt.Errorf("%w: %d and %d buckets", ErrIncompatible, t1.NumBuckets(), t2.NumBuckets())
+	case t1.TagsPerBucket() != t2.TagsPerBucket():
+		return fmt.Errorf("%w: %d and %d tags per bucket", ErrIncompatible, t1.TagsPerBucket(), t2.TagsPerBucket())
+	case t1.BitsPerItem() != t2.BitsPerItem():
+		return fmt.Errorf("%w: %d and %d bits per item", ErrIncompatible, t1.BitsPerItem(), t2.BitsPerItem())
+	case (f.cpp == nil) != (other.cpp == nil) || f.cpp != nil && *f.cpp != *other.cpp:
+		return fmt.Errorf("%w: different hasher", ErrIncompatible)
+	}
+	return nil
+}
+
+// Clone return a deep copy of f, which shares nothing mutable with f
+func (f *Filter) Clone() *Filter {
+	nf := *f
+	nf.table = f.table.clone()
+	nf.vacuum = asVacuum(nf.table)
+	if f.counters != nil {
+		c := *f.counters
+		nf.counters = &c
+	}
+	return &nf
+}
+
+// Equal return if other has the same parameters and stores the same fingerprints in each bucket as f.
+// Slot order inside buckets is ignored, so filters equal logically even if their encodings differ
+func (f *Filter) Equal(other *Filter) bool {
+	if f.compatible(other) != nil || f.numItems != other.numItems || f.victim.used != other.victim.used {
+		return false
+	}
+	if f.victim.used && f.victim != other.victim {
+		return false
+	}
+	tags1 := make([]uint32, f.table.TagsPerBucket())
+	tags2 := make([]uint32, f.table.TagsPerBucket())
+	for i := uint(0); i < f.table.NumBuckets(); i++ {
+		f.table.ReadTagsFromBucket(i, tags1)
+		other.table.ReadTagsFromBucket(i, tags2)
+		sortTags(tags1)
+		sortTags(tags2)
+		for j := range tags1 {
+			if tags1[j] != tags2[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// AddString add a string item into filter without converting it to []byte,
+// it is the same as Add([]byte(item)), return false when filter is full
+func (f *Filter) AddString(item string) bool {
+	if f.tooLong(len(item)) {
+		return false
+	}
+	return f.insert(f.indexTagHash(f.hashString(item)))
+}
+
+// ContainString return if filter contains a string item, it is the same as Contain([]byte(item))
+func (f *Filter) ContainString(item string) bool {
+	if f.tooLong(len(item)) {
+		return false
+	}
+	return f.containImpl(f.indexTagHash(f.hashString(item)))
+}
+
+// DeleteString delete a string item from filter, it is the same as Delete([]byte(item))
+func (f *Filter) DeleteString(item string) bool {
+	if f.tooLong(len(item)) {
+		return false
+	}
+	return f.deleteImpl(f.indexTagHash(f.hashString(item)))
+}
+
+// AddUint64 add an uint64 item into filter, it is the same as Add with item encoded
+// in 8 bytes little-endian, return false when filter is full
+func (f *Filter) AddUint64(item uint64) bool {
+	return f.insert(f.indexTagHash(f.hashUint64(item)))
+}
+
+// ContainUint64 return if filter contains an uint64 item, it is the same as Contain with item encoded
+// in 8 bytes little-endian
+func (f *Filter) ContainUint64(item uint64) bool {
+	return f.containImpl(f.indexTagHash(f.hashUint64(item)))
+}
+
+// DeleteUint64 delete an uint64 item from filter, it is the same as Delete with item encoded
+// in 8 bytes little-endian
+func (f *Filter) DeleteUint64(item uint64) bool {
+	return f.deleteImpl(f.indexTagHash(f.hashUint64(item)))
+}
+
+// AddHash add an item by its 64 bits hash, which is derived into index and tag exactly as the hash
+// computed by Add, that is metro.Hash64(item, 1337), or CppHasher for filters of NewCppFilter and ImportCpp.
+// Any well mixed hash works if all operations on the item use it. Return false when filter is full
+func (f *Filter) AddHash(h uint64) bool {
+	return f.insert(f.indexTagHash(h))
+}
+
+// ContainHash return if filter contains an item by its 64 bits hash, see AddHash
+func (f *Filter) ContainHash(h uint64) bool {
+	return f.containImpl(f.indexTagHash(h))
+}
+
+// DeleteHash delete an item from filter by its 64 bits hash, see AddHash
+func (f *Filter) DeleteHash(h uint64) bool {
+	return f.deleteImpl(f.indexTagHash(h))
+}
+
 // Reset reset the filter
 func (f *Filter) Reset() {
 	f.table.Reset()
@@ -254,6 +870,34 @@ func (f *Filter) Info() string {
 		f.table.Info(), f.Size(), f.LoadFactor(), f.table.SizeInBytes()>>10, f.BitsPerItem())
 }
 
+// EnableDirtyTracking start recording which pages of bucketsPerPage buckets are modified,
+// so that only dirty ranges of the encoded filter need to be persisted, see RangeDirty
+func (f *Filter) EnableDirtyTracking(bucketsPerPage uint) {
+	f.table.EnableDirtyTracking(bucketsPerPage)
+}
+
+// DisableDirtyTracking stop recording modified buckets
+func (f *Filter) DisableDirtyTracking() {
+	f.table.DisableDirtyTracking()
+}
+
+// RangeDirty call fn with offset and length of each byte range of the Encode output that may
+// have changed since last ClearDirty. The filter metadata at the head is always reported,
+// and the whole table is reported when tracking is disabled. Iteration stops when fn return false
+func (f *Filter) RangeDirty(fn func(offset, length uint) bool) {
+	if !fn(0, filterMetadataSize) {
+		return
+	}
+	f.table.RangeDirty(func(offset, length uint) bool {
+		return fn(filterMetadataSize+offset, length)
+	})
+}
+
+// ClearDirty mark the whole filter as clean, usually called after dirty ranges are persisted
+func (f *Filter) ClearDirty() {
+	f.table.ClearDirty()
+}
+
 // Encode returns a byte slice representing a Cuckoo filter
 func (f *Filter) Encode() ([]byte, error) {
 	filterReader, filterSize := f.EncodeReader()
@@ -298,12 +942,22 @@ func DecodeFrom(b []byte) (*Filter, error) {
 	curTag := binary.LittleEndian.Uint32(b[2*1*bytesPerUint32:])
 	used := b[12] == byte(1)
 	tableType := uint(b[13])
+	if err := validateTable(b[13:]); err != nil {
+		return nil, err
+	}
 	table := getTable(tableType).(table)
 	if err := table.Decode(b[13:]); err != nil {
 		return nil, err
 	}
+	if numItems > table.SizeInTags() {
+		return nil, fmt.Errorf("%d items exceed %d slots", numItems, table.SizeInTags())
+	}
+	if used && (curIndex >= table.NumBuckets() || curTag == 0 || curTag>>table.BitsPerItem() != 0) {
+		return nil, fmt.Errorf("invalid victim at bucket %d with tag %d", curIndex, curTag)
+	}
 	return &Filter{
 		table:    table,
+		vacuum:   asVacuum(table),
 		numItems: numItems,
 		victim: victimCache{
 			index: curIndex,
@@ -312,3 +966,34 @@ func DecodeFrom(b []byte) (*Filter, error) {
 		},
 	}, nil
 }
+
+// validateTable check parameters of an encoded table before it is decoded, since encoded filters may come
+// from untrusted sources, and bad parameters make tables panic or allocate a lot
+func validateTable(b []byte) error {
+	cfg := Config{TableType: uint(b[0]), TagsPerBucket: tagsPerPTable}
+	var numBuckets uint
+	switch cfg.TableType {
+	case TableTypeSingle, TableTypeVacuum:
+		cfg.TagsPerBucket, cfg.BitsPerItem = uint(b[1]), uint(b[2])
+		numBuckets = uint(binary.LittleEndian.Uint32(b[3:]))
+		b = b[7:]
+	case TableTypePacked:
+		cfg.BitsPerItem = uint(b[1])
+		numBuckets = uint(binary.LittleEndian.Uint32(b[2:]))
+		b = b[6:]
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return errors.New("unexpected bytes length")
+	}
+	valid := numBuckets > 0 && numBuckets&(numBuckets-1) == 0
+	if cfg.TableType == TableTypeVacuum {
+		valid = vacuumValidNumBuckets(numBuckets, cfg.TagsPerBucket)
+	}
+	if !valid {
+		return fmt.Errorf("invalid num of buckets %d for table type %d", numBuckets, cfg.TableType)
+	}
+	return nil
+}
@@ -8,10 +8,14 @@ package cuckoo
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
 	"testing"
+
+	"github.com/dgryski/go-metro"
 )
 
 const size = 100000
@@ -19,7 +23,7 @@ const size = 100000
 var (
 	testBucketSize      = []uint{2, 4, 8}
 	testFingerprintSize = []uint{2, 4, 5, 6, 7, 8, 9, 10, 12, 13, 16, 17, 23, 31, 32}
-	testTableType       = []uint{TableTypeSingle, TableTypePacked}
+	testTableType       = []uint{TableTypeSingle, TableTypePacked, TableTypeVacuum}
 )
 
 func TestFilter(t *testing.T) {
@@ -110,6 +114,398 @@ func TestFilter(t *testing.T) {
 	}
 }
 
+func TestDecodeInvalid(t *testing.T) {
+	single, _ := NewFilter(4, 8, 1000, TableTypeSingle).Encode()
+	packed, _ := NewFilter(4, 8, 1000, TableTypePacked).Encode()
+	vacuum, _ := NewFilter(4, 8, 100000, TableTypeVacuum).Encode()
+	for _, c := range []struct {
+		name   string
+		b      []byte
+		offset int
+		value  []byte
+	}{
+		{"unknown table type", single, 13, []byte{9}},
+		{"zero tags per bucket", single, 14, []byte{0}},
+		{"zero bits per item", single, 15, []byte{0}},
+		{"too many bits per item", single, 15, []byte{33}},
+		{"zero buckets", single, 16, []byte{0, 0, 0, 0}},
+		{"not power of two buckets", single, 16, []byte{3, 1, 0, 0}},
+		{"too many items", single, 0, []byte{0xff, 0xff, 0, 0}},
+		{"victim out of table", single, 4, []byte{0xff, 0xff, 0, 0, 1, 0, 0, 0, 1}},
+		{"packed too few bits per item", packed, 14, []byte{3}},
+		{"packed zero buckets", packed, 15, []byte{0, 0, 0, 0}},
+		{"vacuum odd buckets", vacuum, 16, []byte{0x33, 0x33, 0, 0}},
+	} {
+		b := append([]byte(nil), c.b...)
+		copy(b[c.offset:], c.value)
+		if _, err := Decode(b); err == nil {
+			t.Errorf("Expected error for %s", c.name)
+		}
+	}
+	if _, err := Decode(single[:20]); err == nil {
+		t.Errorf("Expected error for missing buckets")
+	}
+	for _, b := range [][]byte{single, packed, vacuum} {
+		if _, err := Decode(b); err != nil {
+			t.Fatalf("err %v", err)
+		}
+	}
+}
+
+func TestFilterDirtyTracking(t *testing.T) {
+	var hash [32]byte
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 100000, table)
+		cf.EnableDirtyTracking(64)
+		snapshot, _ := cf.Encode()
+		cf.ClearDirty()
+
+		for i := 0; i < 100; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			cf.Add(hash[:])
+		}
+		cf.Delete(hash[:])
+
+		encodedBytes, _ := cf.Encode()
+		var dirtyBytes uint
+		cf.RangeDirty(func(offset, length uint) bool {
+			copy(snapshot[offset:offset+length], encodedBytes[offset:offset+length])
+			dirtyBytes += length
+			return true
+		})
+		if !bytes.Equal(snapshot, encodedBytes) {
+			t.Fatalf("Expected patched snapshot equal to encoding, table type %v", table)
+		}
+		if dirtyBytes >= uint(len(encodedBytes))/2 {
+			t.Errorf("Expected few dirty bytes, instead %d of %d, table type %v", dirtyBytes, len(encodedBytes), table)
+		}
+
+		cf.ClearDirty()
+		cf.RangeDirty(func(offset, length uint) bool {
+			if offset != 0 {
+				t.Errorf("Expected only metadata dirty after clear, got offset %d, table type %v", offset, table)
+			}
+			return true
+		})
+	}
+}
+
+func TestFilterRange(t *testing.T) {
+	var hash [32]byte
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 1000, table)
+		for i := 0; i < 2000; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			if !cf.Add(hash[:]) {
+				break
+			}
+			i1, tag := cf.generateIndexTagHash(hash[:])
+			i2 := cf.altIndex(i1, tag)
+			found := false
+			cf.Range(func(bucket, slot uint, v uint32) bool {
+				found = v == tag && (bucket == i1 || bucket == i2)
+				return !found
+			})
+			if !found {
+				t.Fatalf("Expected fingerprint to be ranged, table type %v", table)
+			}
+		}
+
+		var count uint
+		cf.Range(func(bucket, slot uint, tag uint32) bool {
+			if slot > 4 || (slot == 4 && (!cf.victim.used || bucket != cf.victim.index)) {
+				t.Fatalf("Unexpected bucket %d slot %d, table type %v", bucket, slot, table)
+			}
+			count++
+			return true
+		})
+		if count != cf.Size() {
+			t.Fatalf("Expected %d fingerprints, instead %d, table type %v", cf.Size(), count, table)
+		}
+	}
+}
+
+func TestFilterConvertTo(t *testing.T) {
+	var hash [32]byte
+	cf := NewFilter(4, 9, 10000, TableTypeSingle)
+	a := make([][]byte, 0)
+	for i := 0; i < 9000; i++ {
+		_, _ = io.ReadFull(rand.Reader, hash[:])
+		tmp := make([]byte, 32)
+		copy(tmp, hash[:])
+		if cf.Add(tmp) {
+			a = append(a, tmp)
+		}
+	}
+
+	packed, err := cf.ConvertTo(TableTypePacked)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	single, err := packed.ConvertTo(TableTypeSingle)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if packed.SizeInBytes() >= cf.SizeInBytes() {
+		t.Errorf("Expected packed table smaller than single table, %d >= %d", packed.SizeInBytes(), cf.SizeInBytes())
+	}
+	for _, ncf := range []*Filter{packed, single} {
+		if ncf.Size() != cf.Size() {
+			t.Fatalf("Expected count = %d, instead count = %d", cf.Size(), ncf.Size())
+		}
+		for _, v := range a {
+			if !ncf.Contain(v) {
+				t.Fatalf("Expected contain after convert")
+			}
+		}
+		for _, v := range a {
+			ncf.Delete(v)
+		}
+		if ncf.Size() != 0 {
+			t.Fatalf("Expected count = 0, instead count == %d", ncf.Size())
+		}
+	}
+
+	if _, err := NewFilter(2, 9, 10000, TableTypeSingle).ConvertTo(TableTypePacked); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible for 2 tags per bucket, instead %v", err)
+	}
+	if _, err := NewFilter(4, 3, 10000, TableTypeSingle).ConvertTo(TableTypePacked); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible for 3 bits per item, instead %v", err)
+	}
+	// same rule as NewFilter and Config.Validate
+	cf = NewFilter(4, 4, 10000, TableTypeSingle)
+	for _, v := range a[:1000] {
+		cf.Add(v)
+	}
+	if packed, err = cf.ConvertTo(TableTypePacked); err != nil || packed.Size() != cf.Size() {
+		t.Fatalf("Expected 4 bits per item converted, err %v", err)
+	}
+	for _, v := range a[:1000] {
+		if !packed.Contain(v) {
+			t.Fatalf("Expected contain after convert with 4 bits per item")
+		}
+	}
+}
+
+func TestFilterCloneEqual(t *testing.T) {
+	var hash [32]byte
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 10000, table)
+		for i := 0; i < 2000; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			cf.Add(hash[:])
+		}
+
+		ncf := cf.Clone()
+		if !cf.Equal(ncf) || !reflect.DeepEqual(cf, ncf) {
+			t.Fatalf("Expected clone equal, table type %v", table)
+		}
+		ncf.Add([]byte("clone"))
+		if cf.Equal(ncf) || cf.Contain([]byte("clone")) {
+			t.Fatalf("Expected clone to be independent, table type %v", table)
+		}
+		ncf.Delete([]byte("clone"))
+		if !cf.Equal(ncf) {
+			t.Fatalf("Expected equal after delete, table type %v", table)
+		}
+	}
+
+	// same fingerprints stored in different slot order
+	cf := NewFilter(4, 9, 10000, TableTypeSingle)
+	ncf := cf.Clone()
+	cf.table.InsertTagToBucket(1, 3, false, nil)
+	cf.table.InsertTagToBucket(1, 5, false, nil)
+	ncf.table.InsertTagToBucket(1, 5, false, nil)
+	ncf.table.InsertTagToBucket(1, 3, false, nil)
+	if !cf.Equal(ncf) {
+		t.Fatalf("Expected equal regardless of slot order")
+	}
+	if cf.Equal(NewFilter(4, 9, 10000, TableTypePacked)) {
+		t.Fatalf("Expected filters of different table type not equal")
+	}
+}
+
+func TestFilterBatch(t *testing.T) {
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 10000, table)
+		items := make([][]byte, 12000)
+		for i := range items {
+			items[i] = make([]byte, 32)
+			_, _ = io.ReadFull(rand.Reader, items[i])
+		}
+
+		inserted, err := cf.AddBatch(items[:5000])
+		if inserted != 5000 || err != nil {
+			t.Fatalf("Expected 5000 inserted, instead %d, err %v, table type %v", inserted, err, table)
+		}
+		out := make([]bool, 5000)
+		cf.ContainBatch(items[:5000], out)
+		for i, v := range out {
+			if !v || !cf.Contain(items[i]) {
+				t.Fatalf("Expected contain, table type %v", table)
+			}
+		}
+		cf.ContainBatch(items[5000:10000], out)
+		for i, v := range out {
+			if v != cf.Contain(items[5000+i]) {
+				t.Fatalf("Expected batch lookup equal to single lookup, table type %v", table)
+			}
+		}
+		if allocs := testing.AllocsPerRun(10, func() { cf.ContainBatch(items[:5000], out) }); allocs != 0 {
+			t.Errorf("Expected batch lookup without allocation, instead %v, table type %v", allocs, table)
+		}
+
+		inserted, err = cf.AddBatch(items)
+		if !errors.Is(err, ErrFilterFull) || uint(inserted+5000) != cf.Size() {
+			t.Fatalf("Expected ErrFilterFull with %d inserted, instead %d, err %v, table type %v", cf.Size()-5000, inserted, err, table)
+		}
+		// victim is looked up too once filter is full
+		out = make([]bool, len(items))
+		cf.ContainBatch(items, out)
+		for i, v := range out {
+			if v != cf.Contain(items[i]) {
+				t.Fatalf("Expected batch lookup equal to single lookup on full filter, table type %v", table)
+			}
+		}
+	}
+}
+
+func TestFilterStringUint64(t *testing.T) {
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 10000, table)
+		b := make([]byte, 8)
+		for i := uint64(0); i < 1000; i++ {
+			s := fmt.Sprintf("item-%d", i)
+			binary.LittleEndian.PutUint64(b, i)
+			cf.AddString(s)
+			cf.AddUint64(i)
+			if !cf.Contain([]byte(s)) || !cf.ContainString(s) || !cf.Contain(b) || !cf.ContainUint64(i) {
+				t.Fatalf("Expected same fingerprint as byte encoding, table type %v", table)
+			}
+		}
+		for i := uint64(0); i < 1000; i++ {
+			binary.LittleEndian.PutUint64(b, i)
+			if !cf.DeleteString(fmt.Sprintf("item-%d", i)) || !cf.Delete(b) {
+				t.Fatalf("Expected delete, table type %v", table)
+			}
+		}
+		if cf.Size() != 0 {
+			t.Fatalf("Expected count = 0, instead count == %d, table type %v", cf.Size(), table)
+		}
+
+		s := "allocation free"
+		allocs := testing.AllocsPerRun(100, func() {
+			cf.AddString(s)
+			cf.ContainString(s)
+			cf.DeleteString(s)
+			cf.AddUint64(42)
+			cf.ContainUint64(42)
+			cf.DeleteUint64(42)
+		})
+		if allocs != 0 {
+			t.Errorf("Expected no allocation, instead %v, table type %v", allocs, table)
+		}
+	}
+
+	cf := NewCppFilter(12, 10000, CppHasher{MultiplyHi: 1, MultiplyLo: 3, AddLo: 5})
+	// items of cpp filters are at most 8 bytes
+	s := "8 bytes!"
+	allocs := testing.AllocsPerRun(100, func() {
+		cf.AddString(s)
+		cf.ContainString(s)
+		cf.DeleteString(s)
+		cf.AddUint64(42)
+		cf.ContainUint64(42)
+		cf.DeleteUint64(42)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocation with cpp hasher, instead %v", allocs)
+	}
+}
+
+func TestFilterHash(t *testing.T) {
+	var hash [32]byte
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 10000, table)
+		a := make([][]byte, 0)
+		for i := 0; i < 5000; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			tmp := make([]byte, 32)
+			copy(tmp, hash[:])
+			a = append(a, tmp)
+			if !cf.AddHash(metro.Hash64(tmp, 1337)) {
+				t.Fatalf("Expected add ok, table type %v", table)
+			}
+		}
+		for _, v := range a {
+			if !cf.Contain(v) || !cf.ContainHash(metro.Hash64(v, 1337)) {
+				t.Fatalf("Expected contain, table type %v", table)
+			}
+		}
+		for _, v := range a {
+			if !cf.DeleteHash(metro.Hash64(v, 1337)) {
+				t.Fatalf("Expected delete, table type %v", table)
+			}
+		}
+		if cf.Size() != 0 {
+			t.Fatalf("Expected count = 0, instead count == %d, table type %v", cf.Size(), table)
+		}
+	}
+}
+
+func TestFilterMerge(t *testing.T) {
+	var hash [32]byte
+	for _, table := range testTableType {
+		cf1 := NewFilter(4, 13, 10000, table)
+		cf2 := NewFilter(4, 13, 10000, table)
+		a := make([][]byte, 0)
+		for i := 0; i < 8000; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			tmp := make([]byte, 32)
+			copy(tmp, hash[:])
+			a = append(a, tmp)
+			if i%2 == 0 {
+				cf1.Add(tmp)
+			} else {
+				cf2.Add(tmp)
+			}
+		}
+
+		if err := cf1.Merge(cf2); err != nil {
+			t.Fatalf("err %v, table type %v", err, table)
+		}
+		if cf1.Size() != uint(len(a)) {
+			t.Fatalf("Expected count = %d, instead count = %d, table type %v", len(a), cf1.Size(), table)
+		}
+		for _, v := range a {
+			if !cf1.Contain(v) {
+				t.Fatalf("Expected contain after merge, table type %v", table)
+			}
+		}
+
+		cf3 := NewFilter(4, 13, 10000, table)
+		for i := 0; i < 8300; i++ {
+			_, _ = io.ReadFull(rand.Reader, hash[:])
+			cf3.Add(hash[:])
+		}
+		before, _ := cf1.Encode()
+		if err := cf1.Merge(cf3); !errors.Is(err, ErrFilterFull) {
+			t.Fatalf("Expected ErrFilterFull, instead %v", err)
+		}
+		after, _ := cf1.Encode()
+		if !bytes.Equal(before, after) {
+			t.Fatalf("Expected filter unchanged after failed merge, table type %v", table)
+		}
+
+		if err := cf1.Merge(NewFilter(4, 13, 100000, table)); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("Expected ErrIncompatible, instead %v", err)
+		}
+		if err := cf1.Merge(NewFilter(4, 9, 10000, table)); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("Expected ErrIncompatible, instead %v", err)
+		}
+	}
+}
+
 func BenchmarkFilterSingle_Reset(b *testing.B) {
 	filter := NewFilter(4, 8, size, TableTypeSingle)
 
@@ -148,6 +544,164 @@ func BenchmarkFilterSingle_Lookup(b *testing.B) {
 	}
 }
 
+func benchmarkKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, 16)
+		_, _ = io.ReadFull(rand.Reader, keys[i])
+	}
+	return keys
+}
+
+// batchSize fill most of a filter with 1M buckets
+const batchSize = 1 << 21
+
+func BenchmarkFilterSingle_InsertLoop(b *testing.B) {
+	filter := NewFilter(4, 8, 3500000, TableTypeSingle)
+	keys := benchmarkKeys(batchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Reset()
+		for _, key := range keys {
+			filter.Add(key)
+		}
+	}
+}
+
+func BenchmarkFilterSingle_InsertBatch(b *testing.B) {
+	filter := NewFilter(4, 8, 3500000, TableTypeSingle)
+	keys := benchmarkKeys(batchSize)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Reset()
+		_, _ = filter.AddBatch(keys)
+	}
+}
+
+func BenchmarkFilterSingle_LookupLoop(b *testing.B) {
+	filter := NewFilter(4, 8, 3500000, TableTypeSingle)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			filter.Contain(key)
+		}
+	}
+}
+
+func BenchmarkFilterSingle_LookupBatch(b *testing.B) {
+	filter := NewFilter(4, 8, 3500000, TableTypeSingle)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+	out := make([]bool, len(keys))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.ContainBatch(keys, out)
+	}
+}
+
+func BenchmarkFilterPacked_InsertLoop(b *testing.B) {
+	filter := NewFilter(4, 9, 3500000, TableTypePacked)
+	keys := benchmarkKeys(batchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Reset()
+		for _, key := range keys {
+			filter.Add(key)
+		}
+	}
+}
+
+func BenchmarkFilterPacked_InsertBatch(b *testing.B) {
+	filter := NewFilter(4, 9, 3500000, TableTypePacked)
+	keys := benchmarkKeys(batchSize)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Reset()
+		_, _ = filter.AddBatch(keys)
+	}
+}
+
+func BenchmarkFilterPacked_LookupLoop(b *testing.B) {
+	filter := NewFilter(4, 9, 3500000, TableTypePacked)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			filter.Contain(key)
+		}
+	}
+}
+
+func BenchmarkFilterPacked_LookupBatch(b *testing.B) {
+	filter := NewFilter(4, 9, 3500000, TableTypePacked)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+	out := make([]bool, len(keys))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.ContainBatch(keys, out)
+	}
+}
+
+func BenchmarkFilterSingle_InsertString(b *testing.B) {
+	filter := NewFilter(4, 8, size, TableTypeSingle)
+	s := "benchmark key"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.AddString(s)
+		filter.DeleteString(s)
+	}
+}
+
+func BenchmarkFilterSingle_LookupString(b *testing.B) {
+	filter := NewFilter(4, 8, size, TableTypeSingle)
+	s := "benchmark key"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.ContainString(s)
+	}
+}
+
+func BenchmarkFilterSingle_InsertUint64(b *testing.B) {
+	filter := NewFilter(4, 8, size, TableTypeSingle)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.AddUint64(uint64(i))
+		filter.DeleteUint64(uint64(i))
+	}
+}
+
+func BenchmarkFilterSingle_LookupUint64(b *testing.B) {
+	filter := NewFilter(4, 8, size, TableTypeSingle)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.ContainUint64(uint64(i))
+	}
+}
+
 func BenchmarkFilterPacked_Reset(b *testing.B) {
 	filter := NewFilter(4, 9, size, TableTypePacked)
 
@@ -185,3 +739,121 @@ func BenchmarkFilterPacked_Lookup(b *testing.B) {
 		filter.Contain(hash[:])
 	}
 }
+
+func TestFilterCount(t *testing.T) {
+	for _, table := range testTableType {
+		cf := NewFilter(4, 16, 1000, table)
+		item := []byte("item")
+		for i := uint(1); i <= 5; i++ {
+			cf.Add(item)
+			if c := cf.Count(item); c != i {
+				t.Fatalf("Expected count %d, instead %d, table type %v", i, c, table)
+			}
+		}
+		cf.Delete(item)
+		if c := cf.Count(item); c != 4 {
+			t.Fatalf("Expected count 4 after delete, instead %d", c)
+		}
+		if c := cf.Count([]byte("other")); c != 0 {
+			t.Fatalf("Expected count 0, instead %d", c)
+		}
+	}
+}
+
+func TestVacuumFilter(t *testing.T) {
+	const n = 1100000
+	cf := NewFilter(4, 12, n, TableTypeVacuum)
+	pow2 := NewFilter(4, 12, n, TableTypeSingle)
+	if cf.SizeInBytes()*3 > pow2.SizeInBytes()*2 {
+		t.Fatalf("Expected vacuum table much smaller than power of two table, %d and %d", cf.SizeInBytes(), pow2.SizeInBytes())
+	}
+	vt := cf.table.(*VacuumTable)
+	if vt.NumBuckets()&(vt.NumBuckets()-1) == 0 || vt.NumBuckets()%vt.ChunkSize() != 0 {
+		t.Fatalf("Unexpected %d buckets with chunk size %d", vt.NumBuckets(), vt.ChunkSize())
+	}
+	if err := NewVacuumTable().Init(4, 12, vt.NumBuckets()+1, nil); err == nil {
+		t.Fatalf("Expected error for num of buckets not a multiple of min chunk")
+	}
+	if cf.Clone().vacuum == vt {
+		t.Fatalf("Expected clone indexed by its own table")
+	}
+
+	item := make([]byte, 8)
+	for i := uint64(0); i < n; i++ {
+		binary.LittleEndian.PutUint64(item, i)
+		if !cf.Add(item) {
+			t.Fatalf("Expected add ok, load factor %v", cf.LoadFactor())
+		}
+		index, tag := cf.generateIndexTagHash(item)
+		alt := cf.altIndex(index, tag)
+		if index >= vt.NumBuckets() || alt/vt.ChunkSize() != index/vt.ChunkSize() || cf.altIndex(alt, tag) != index {
+			t.Fatalf("Unexpected bucket pair %d and %d", index, alt)
+		}
+	}
+	for i := uint64(0); i < n; i += 2 {
+		binary.LittleEndian.PutUint64(item, i)
+		if !cf.Delete(item) {
+			t.Fatalf("Expected delete ok")
+		}
+	}
+	for i := uint64(1); i < n; i += 2 {
+		binary.LittleEndian.PutUint64(item, i)
+		if !cf.Contain(item) {
+			t.Fatalf("Expected contain after delete")
+		}
+	}
+
+	if _, err := cf.ConvertTo(TableTypeSingle); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible converting vacuum table, instead %v", err)
+	}
+	if _, err := pow2.ConvertTo(TableTypeVacuum); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("Expected ErrIncompatible converting to vacuum table, instead %v", err)
+	}
+
+	keys := make([][]byte, 30000)
+	for i := range keys {
+		keys[i] = make([]byte, 32)
+		_, _ = io.ReadFull(rand.Reader, keys[i])
+	}
+	built, err := BuildFilter(keys, Config{TagsPerBucket: 4, BitsPerItem: 12, TableType: TableTypeVacuum})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if built.Size() != uint(len(keys)) || built.LoadFactor() < 0.9 {
+		t.Fatalf("Expected %d keys with high load factor, instead %d with %v", len(keys), built.Size(), built.LoadFactor())
+	}
+	for _, key := range keys {
+		if !built.Contain(key) {
+			t.Fatalf("Expected built filter contain")
+		}
+	}
+}
+
+// largeNumKeys plan a filter of 16M buckets, 128MB for 16 bits per item, which is far larger than cpu caches
+const largeNumKeys = 1 << 26
+
+func BenchmarkFilterSingleLarge_LookupLoop(b *testing.B) {
+	filter := NewFilter(4, 16, largeNumKeys, TableTypeSingle)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			filter.Contain(key)
+		}
+	}
+}
+
+func BenchmarkFilterSingleLarge_LookupBatch(b *testing.B) {
+	filter := NewFilter(4, 16, largeNumKeys, TableTypeSingle)
+	_, _ = filter.AddBatch(benchmarkKeys(batchSize))
+	keys := benchmarkKeys(batchSize)
+	out := make([]bool, len(keys))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.ContainBatch(keys, out)
+	}
+}
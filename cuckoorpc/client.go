@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 20:00
+ */
+
+package cuckoorpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"strings"
+	"sync"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+// Interface is the method set shared by *cuckoo.Filter and *RemoteFilter,
+// so code written against it works with local and remote filters
+type Interface interface {
+	Add(item []byte) bool
+	AddUnique(item []byte) bool
+	Contain(item []byte) bool
+	Delete(item []byte) bool
+	Count(item []byte) uint
+	AddBatch(items [][]byte) (int, error)
+	ContainBatch(items [][]byte, out []bool)
+	Size() uint
+	LoadFactor() float64
+	Stats() cuckoo.Stats
+	Info() string
+	Reset()
+	Encode() ([]byte, error)
+	EncodeReader() (io.Reader, uint)
+	Merge(other *cuckoo.Filter) error
+}
+
+var (
+	_ Interface = (*cuckoo.Filter)(nil)
+	_ Interface = (*RemoteFilter)(nil)
+)
+
+// RemoteFilter is a filter of a Service called through a rpc.Client, it is safe for concurrent use.
+// Methods without an error result return zero values when a call fails, except Contain and ContainBatch
+// which report items as contained, since a filter may return false positives but never false negatives.
+// The first failure is kept until it is returned by Err
+type RemoteFilter struct {
+	client *rpc.Client
+	name   string
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewRemoteFilter return a RemoteFilter of filter name served by client
+func NewRemoteFilter(client *rpc.Client, name string) *RemoteFilter {
+	return &RemoteFilter{client: client, name: name}
+}
+
+// CreateRemoteFilter create filter name in the service and return it, see cuckoo.NewFilter for the parameters
+func CreateRemoteFilter(client *rpc.Client, name string, tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) (*RemoteFilter, error) {
+	args := CreateArgs{Name: name, TagsPerBucket: tagsPerBucket, BitsPerItem: bitsPerItem, MaxNumKeys: maxNumKeys, TableType: tableType}
+	if err := client.Call(ServiceName+".Create", args, &struct{}{}); err != nil {
+		return nil, err
+	}
+	return NewRemoteFilter(client, name), nil
+}
+
+// Name return name of the filter in the service
+func (r *RemoteFilter) Name() string {
+	return r.name
+}
+
+// Err return the first error of calls whose methods do not return errors since last call of Err, and clear it
+func (r *RemoteFilter) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.err
+	r.err = nil
+	return err
+}
+
+// call call method and return the error, errors of cuckoo package are restored so errors.Is works
+func (r *RemoteFilter) call(method string, args, reply interface{}) error {
+	err := r.client.Call(ServiceName+"."+method, args, reply)
+	var serverErr rpc.ServerError
+	if errors.As(err, &serverErr) {
+		for _, target := range []error{cuckoo.ErrFilterFull, cuckoo.ErrIncompatible} {
+			if msg := string(serverErr); strings.HasPrefix(msg, target.Error()) {
+				return fmt.Errorf("%w%s", target, strings.TrimPrefix(msg, target.Error()))
+			}
+		}
+	}
+	return err
+}
+
+// do call method and keep the error
+func (r *RemoteFilter) do(method string, args, reply interface{}) bool {
+	err := r.call(method, args, reply)
+	if err != nil {
+		r.mu.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mu.Unlock()
+	}
+	return err == nil
+}
+
+// Add add an item into filter, return false when filter is full
+func (r *RemoteFilter) Add(item []byte) bool {
+	var ok bool
+	r.do("Add", KeyArgs{Name: r.name, Key: item}, &ok)
+	return ok
+}
+
+// AddUnique add an item into filter, return false when filter already contains it or filter is full
+func (r *RemoteFilter) AddUnique(item []byte) bool {
+	var ok bool
+	r.do("AddUnique", KeyArgs{Name: r.name, Key: item}, &ok)
+	return ok
+}
+
+// Contain return if filter contains an item, it return true when the call fails
+func (r *RemoteFilter) Contain(item []byte) bool {
+	var ok bool
+	return !r.do("Contain", KeyArgs{Name: r.name, Key: item}, &ok) || ok
+}
+
+// Delete delete item from filter, return false when item not exist
+func (r *RemoteFilter) Delete(item []byte) bool {
+	var ok bool
+	r.do("Delete", KeyArgs{Name: r.name, Key: item}, &ok)
+	return ok
+}
+
+// Count return num of times an item may have been added
+func (r *RemoteFilter) Count(item []byte) uint {
+	var n uint
+	r.do("Count", KeyArgs{Name: r.name, Key: item}, &n)
+	return n
+}
+
+// AddBatch add items into filter, return num of items inserted, and ErrFilterFull when filter becomes full
+func (r *RemoteFilter) AddBatch(items [][]byte) (int, error) {
+	var reply BatchReply
+	if err := r.call("AddBatch", KeysArgs{Name: r.name, Keys: items}, &reply); err != nil {
+		return 0, err
+	}
+	if reply.Full {
+		return reply.Inserted, cuckoo.ErrFilterFull
+	}
+	return reply.Inserted, nil
+}
+
+// ContainBatch set out[i] to whether filter contains items[i], all of them are set to true when the call fails
+func (r *RemoteFilter) ContainBatch(items [][]byte, out []bool) {
+	_ = out[:len(items)]
+	var reply []bool
+	if r.do("ContainBatch", KeysArgs{Name: r.name, Keys: items}, &reply) {
+		copy(out, reply)
+		return
+	}
+	for i := range items {
+		out[i] = true
+	}
+}
+
+// Size return num of items that filter store
+func (r *RemoteFilter) Size() uint {
+	var n uint
+	r.do("Size", NameArgs{Name: r.name}, &n)
+	return n
+}
+
+// LoadFactor return current filter's loadFactor
+func (r *RemoteFilter) LoadFactor() float64 {
+	var f float64
+	r.do("LoadFactor", NameArgs{Name: r.name}, &f)
+	return f
+}
+
+// Stats return a snapshot of filter's status
+func (r *RemoteFilter) Stats() cuckoo.Stats {
+	var s cuckoo.Stats
+	r.do("Stats", NameArgs{Name: r.name}, &s)
+	return s
+}
+
+// Info return filter's detail info
+func (r *RemoteFilter) Info() string {
+	var s string
+	r.do("Info", NameArgs{Name: r.name}, &s)
+	return s
+}
+
+// Reset reset the filter
+func (r *RemoteFilter) Reset() {
+	r.do("Reset", NameArgs{Name: r.name}, &struct{}{})
+}
+
+// Encode returns a byte slice representing the filter
+func (r *RemoteFilter) Encode() ([]byte, error) {
+	var b []byte
+	if err := r.call("Snapshot", NameArgs{Name: r.name}, &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// EncodeReader returns a reader representing the filter, the filter is transferred when called.
+// Size is 0 when the call fails
+func (r *RemoteFilter) EncodeReader() (io.Reader, uint) {
+	var b []byte
+	r.do("Snapshot", NameArgs{Name: r.name}, &b)
+	return bytes.NewReader(b), uint(len(b))
+}
+
+// Merge insert all items of other into filter, see Filter.Merge
+func (r *RemoteFilter) Merge(other *cuckoo.Filter) error {
+	b, err := other.Encode()
+	if err != nil {
+		return err
+	}
+	return r.call("Merge", DataArgs{Name: r.name, Data: b}, &struct{}{})
+}
+
+// Load replace the filter with f
+func (r *RemoteFilter) Load(f *cuckoo.Filter) error {
+	b, err := f.Encode()
+	if err != nil {
+		return err
+	}
+	return r.call("Load", DataArgs{Name: r.name, Data: b}, &struct{}{})
+}
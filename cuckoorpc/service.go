@@ -0,0 +1,287 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 20:00
+ */
+
+// Package cuckoorpc serve named cuckoo filters with net/rpc, and provide RemoteFilter,
+// a client with the same method set as Filter, see Interface
+package cuckoorpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/rpc"
+	"sync"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+// ServiceName is the name Service is registered with
+const ServiceName = "Cuckoo"
+
+// CreateArgs is the arguments of Service.Create, see cuckoo.NewFilter
+type CreateArgs struct {
+	Name          string
+	TagsPerBucket uint
+	BitsPerItem   uint
+	MaxNumKeys    uint
+	TableType     uint
+}
+
+// NameArgs is the arguments of operations on the whole filter
+type NameArgs struct {
+	Name string
+}
+
+// KeyArgs is the arguments of single key operations
+type KeyArgs struct {
+	Name string
+	Key  []byte
+}
+
+// KeysArgs is the arguments of batch operations
+type KeysArgs struct {
+	Name string
+	Keys [][]byte
+}
+
+// DataArgs is the arguments of operations with an encoded filter
+type DataArgs struct {
+	Name string
+	Data []byte
+}
+
+// BatchReply is the reply of Service.AddBatch
+type BatchReply struct {
+	Inserted int
+	Full     bool
+}
+
+// Service is a net/rpc service of named filters, its exported methods with args and reply are the RPC methods
+type Service struct {
+	mu      sync.RWMutex
+	filters map[string]*cuckoo.SyncFilter
+	// MaxNumKeys limit MaxNumKeys of created filters, which decides memory of them
+	MaxNumKeys uint
+}
+
+// DefaultMaxNumKeys is the default MaxNumKeys, whose filter takes 512MB at most with 32 bits per item
+const DefaultMaxNumKeys = 1 << 26
+
+// NewService return a service without filters
+func NewService() *Service {
+	return &Service{filters: make(map[string]*cuckoo.SyncFilter), MaxNumKeys: DefaultMaxNumKeys}
+}
+
+// Register register s in server with ServiceName
+func Register(server *rpc.Server, s *Service) error {
+	return server.RegisterName(ServiceName, s)
+}
+
+// Set add or replace filter with name, it is not a RPC method
+func (s *Service) Set(name string, f *cuckoo.Filter) {
+	s.mu.Lock()
+	s.filters[name] = guard(f)
+	s.mu.Unlock()
+}
+
+// guard return f guarded for concurrent calls, with counters enabled for stats
+func guard(f *cuckoo.Filter) *cuckoo.SyncFilter {
+	f.EnableCounters()
+	return cuckoo.NewSyncFilter(f)
+}
+
+func (s *Service) filter(name string) (*cuckoo.SyncFilter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.filters[name]
+	if !ok {
+		return nil, fmt.Errorf("filter %q not found", name)
+	}
+	return f, nil
+}
+
+// Create create a filter, fail if it exists
+func (s *Service) Create(args CreateArgs, _ *struct{}) error {
+	cfg := cuckoo.Config{TagsPerBucket: args.TagsPerBucket, BitsPerItem: args.BitsPerItem, TableType: args.TableType}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if args.MaxNumKeys > s.MaxNumKeys {
+		return fmt.Errorf("max num of keys should be at most %d but got %d", s.MaxNumKeys, args.MaxNumKeys)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.filters[args.Name]; ok {
+		return fmt.Errorf("filter %q already exists", args.Name)
+	}
+	s.filters[args.Name] = guard(cuckoo.NewFilter(args.TagsPerBucket, args.BitsPerItem, args.MaxNumKeys, args.TableType))
+	return nil
+}
+
+// Drop remove a filter
+func (s *Service) Drop(args NameArgs, _ *struct{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.filters[args.Name]; !ok {
+		return fmt.Errorf("filter %q not found", args.Name)
+	}
+	delete(s.filters, args.Name)
+	return nil
+}
+
+func (s *Service) keyOp(args KeyArgs, reply *bool, op func(f *cuckoo.SyncFilter, key []byte) bool) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = op(f, args.Key)
+	return nil
+}
+
+// Add add a key, reply false when filter is full
+func (s *Service) Add(args KeyArgs, reply *bool) error {
+	return s.keyOp(args, reply, (*cuckoo.SyncFilter).Add)
+}
+
+// AddUnique add a key, reply false when filter already contains it or filter is full
+func (s *Service) AddUnique(args KeyArgs, reply *bool) error {
+	return s.keyOp(args, reply, (*cuckoo.SyncFilter).AddUnique)
+}
+
+// Contain reply if filter contains a key
+func (s *Service) Contain(args KeyArgs, reply *bool) error {
+	return s.keyOp(args, reply, (*cuckoo.SyncFilter).Contain)
+}
+
+// Delete delete a key, reply false when key not exist
+func (s *Service) Delete(args KeyArgs, reply *bool) error {
+	return s.keyOp(args, reply, (*cuckoo.SyncFilter).Delete)
+}
+
+// Count reply num of times a key may have been added
+func (s *Service) Count(args KeyArgs, reply *uint) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	f.Do(func(f *cuckoo.Filter) {
+		*reply = f.Count(args.Key)
+	})
+	return nil
+}
+
+// AddBatch add keys, see Filter.AddBatch
+func (s *Service) AddBatch(args KeysArgs, reply *BatchReply) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	inserted, err := f.AddBatch(args.Keys)
+	*reply = BatchReply{Inserted: inserted, Full: err == cuckoo.ErrFilterFull}
+	return nil
+}
+
+// ContainBatch reply whether filter contains each key
+func (s *Service) ContainBatch(args KeysArgs, reply *[]bool) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = make([]bool, len(args.Keys))
+	f.ContainBatch(args.Keys, *reply)
+	return nil
+}
+
+// Size reply num of items that filter store
+func (s *Service) Size(args NameArgs, reply *uint) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = f.Size()
+	return nil
+}
+
+// LoadFactor reply filter's load factor
+func (s *Service) LoadFactor(args NameArgs, reply *float64) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = f.LoadFactor()
+	return nil
+}
+
+// Stats reply filter's stats
+func (s *Service) Stats(args NameArgs, reply *cuckoo.Stats) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = f.Stats()
+	return nil
+}
+
+// Info reply filter's detail info
+func (s *Service) Info(args NameArgs, reply *string) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	*reply = f.Info()
+	return nil
+}
+
+// Reset reset filter
+func (s *Service) Reset(args NameArgs, _ *struct{}) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	f.Reset()
+	return nil
+}
+
+// Snapshot reply the encoded filter, read from EncodeReader
+func (s *Service) Snapshot(args NameArgs, reply *[]byte) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	f.Do(func(f *cuckoo.Filter) {
+		r, size := f.EncodeReader()
+		buf.Grow(int(size))
+		_, err = io.Copy(&buf, r)
+	})
+	*reply = buf.Bytes()
+	return err
+}
+
+// Load create or replace a filter with an encoded one
+func (s *Service) Load(args DataArgs, _ *struct{}) error {
+	f, err := cuckoo.DecodeFrom(args.Data)
+	if err != nil {
+		return err
+	}
+	s.Set(args.Name, f)
+	return nil
+}
+
+// Merge merge an encoded filter into filter, see Filter.Merge
+func (s *Service) Merge(args DataArgs, _ *struct{}) error {
+	f, err := s.filter(args.Name)
+	if err != nil {
+		return err
+	}
+	other, err := cuckoo.DecodeFrom(args.Data)
+	if err != nil {
+		return err
+	}
+	f.Do(func(f *cuckoo.Filter) {
+		err = f.Merge(other)
+	})
+	return err
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 20:00
+ */
+
+package cuckoorpc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"strconv"
+	"testing"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+func dialService(t *testing.T, s *Service) *rpc.Client {
+	server := rpc.NewServer()
+	if err := Register(server, s); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	c1, c2 := net.Pipe()
+	go server.ServeConn(c1)
+	client := rpc.NewClient(c2)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// exercise use f only through Interface
+func exercise(t *testing.T, f Interface) {
+	var items [][]byte
+	for i := 0; i < 1000; i++ {
+		items = append(items, []byte("item"+strconv.Itoa(i)))
+	}
+	for _, item := range items[:500] {
+		if !f.Add(item) {
+			t.Fatalf("Expected add ok")
+		}
+	}
+	if inserted, err := f.AddBatch(items[500:]); inserted != 500 || err != nil {
+		t.Fatalf("Expected 500 inserted, instead %d, err %v", inserted, err)
+	}
+	if f.AddUnique(items[0]) || f.Count(items[0]) != 1 {
+		t.Fatalf("Expected item contained once")
+	}
+	if !f.Delete(items[0]) || f.Contain(items[0]) {
+		t.Fatalf("Expected item deleted")
+	}
+	out := make([]bool, len(items))
+	f.ContainBatch(items, out)
+	for i, ok := range out[1:] {
+		if !ok {
+			t.Fatalf("Expected contain item %d", i+1)
+		}
+	}
+	if f.Size() != 999 || f.Stats().Items != 999 || f.LoadFactor() == 0 || f.Info() == "" {
+		t.Fatalf("Unexpected size %d", f.Size())
+	}
+
+	r, size := f.EncodeReader()
+	b, _ := io.ReadAll(r)
+	if uint(len(b)) != size {
+		t.Fatalf("Expected %d bytes, instead %d", size, len(b))
+	}
+	snapshot, err := cuckoo.Decode(b)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	f.Reset()
+	if err := f.Merge(snapshot); err != nil || f.Size() != 999 {
+		t.Fatalf("Expected merged size 999, instead %d, err %v", f.Size(), err)
+	}
+	if err := f.Merge(cuckoo.NewFilter(4, 9, 100, cuckoo.TableTypeSingle)); !errors.Is(err, cuckoo.ErrIncompatible) {
+		t.Fatalf("Expected ErrIncompatible, instead %v", err)
+	}
+}
+
+func TestRemoteFilter(t *testing.T) {
+	exercise(t, cuckoo.NewFilter(4, 12, 2000, cuckoo.TableTypePacked))
+
+	s := NewService()
+	client := dialService(t, s)
+	rf, err := CreateRemoteFilter(client, "f", 4, 12, 2000, cuckoo.TableTypePacked)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	exercise(t, rf)
+	if rf.Err() != nil {
+		t.Fatalf("err %v", rf.Err())
+	}
+
+	if _, err := CreateRemoteFilter(client, "f", 4, 12, 2000, cuckoo.TableTypePacked); err == nil {
+		t.Errorf("Expected error creating existing filter")
+	}
+	if _, err := CreateRemoteFilter(client, "g", 2, 12, 2000, cuckoo.TableTypePacked); err == nil {
+		t.Errorf("Expected error for invalid parameters")
+	}
+	if _, err := CreateRemoteFilter(client, "g", 4, 32, 1<<40, cuckoo.TableTypeSingle); err == nil {
+		t.Errorf("Expected error for too many keys")
+	}
+
+	copied := NewRemoteFilter(client, "copy")
+	if err := copied.Load(cuckoo.NewFilter(4, 8, 100, cuckoo.TableTypeSingle)); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	copied.Add([]byte("a"))
+	if !copied.Contain([]byte("a")) {
+		t.Fatalf("Expected contain after load")
+	}
+
+	// a single table of zero bits per item, which would make later calls panic and kill the server
+	crafted := append(make([]byte, 13), cuckoo.TableTypeSingle, 4, 0, 1, 0, 0, 0)
+	if err := client.Call(ServiceName+".Load", DataArgs{Name: "copy", Data: crafted}, &struct{}{}); err == nil {
+		t.Fatalf("Expected error loading invalid filter")
+	}
+	if err := client.Call(ServiceName+".Merge", DataArgs{Name: "copy", Data: crafted}, &struct{}{}); err == nil {
+		t.Fatalf("Expected error merging invalid filter")
+	}
+	if !copied.Contain([]byte("a")) || copied.Err() != nil {
+		t.Fatalf("Expected filter kept after invalid load")
+	}
+
+	// failed lookups report items as contained, and the error is cleared once returned
+	missing := NewRemoteFilter(client, "missing")
+	out := []bool{false, false}
+	missing.ContainBatch([][]byte{[]byte("a"), []byte("b")}, out)
+	if !missing.Contain([]byte("a")) || !out[0] || !out[1] || missing.Err() == nil {
+		t.Fatalf("Expected error for missing filter")
+	}
+	if missing.Err() != nil {
+		t.Fatalf("Expected error cleared")
+	}
+	if missing.Size() != 0 || missing.Err() == nil {
+		t.Fatalf("Expected error again for missing filter")
+	}
+}
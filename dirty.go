@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/18 22:10
+ */
+
+package cuckoo
+
+// dirtyPages record which pages of buckets have been written since last clear, one bit per page
+type dirtyPages struct {
+	pageShift uint
+	numPages  uint
+	bits      []uint64
+}
+
+func (d *dirtyPages) enabled() bool {
+	return d.bits != nil
+}
+
+// init start tracking numBuckets buckets, bucketsPerPage is rounded up to a power of two
+func (d *dirtyPages) init(numBuckets, bucketsPerPage uint) {
+	if bucketsPerPage == 0 {
+		bucketsPerPage = 1
+	}
+	d.pageShift = 0
+	for uint(1)<<d.pageShift < bucketsPerPage {
+		d.pageShift++
+	}
+	d.numPages = (numBuckets + (1 << d.pageShift) - 1) >> d.pageShift
+	d.bits = make([]uint64, (d.numPages+63)>>6)
+}
+
+func (d *dirtyPages) disable() {
+	d.pageShift = 0
+	d.numPages = 0
+	d.bits = nil
+}
+
+func (d *dirtyPages) mark(i uint) {
+	if d.bits == nil {
+		return
+	}
+	p := i >> d.pageShift
+	d.bits[p>>6] |= 1 << (p & 63)
+}
+
+func (d *dirtyPages) markAll() {
+	for p := uint(0); p < d.numPages; p++ {
+		d.bits[p>>6] |= 1 << (p & 63)
+	}
+}
+
+func (d *dirtyPages) clear() {
+	for i := range d.bits {
+		d.bits[i] = 0
+	}
+}
+
+func (d *dirtyPages) isDirty(p uint) bool {
+	return d.bits[p>>6]&(1<<(p&63)) != 0
+}
+
+// rangeDirty call fn with [first, end) bucket range of each run of adjacent dirty pages
+func (d *dirtyPages) rangeDirty(fn func(first, end uint) bool) {
+	for p := uint(0); p < d.numPages; p++ {
+		if !d.isDirty(p) {
+			continue
+		}
+		start := p
+		for p+1 < d.numPages && d.isDirty(p+1) {
+			p++
+		}
+		if !fn(start<<d.pageShift, (p+1)<<d.pageShift) {
+			return
+		}
+	}
+}
@@ -0,0 +1,326 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/18 22:40
+ */
+
+package cuckoo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	walOpAdd    = 1
+	walOpDelete = 2
+
+	// generation number stored at the head of both snapshot and log file
+	walHeaderSize = bytesPerUint64
+	// op + index + tag + crc32
+	walRecordSize = 1 + 3*bytesPerUint32
+)
+
+// walRecord is one logged operation, identified by the index and tag it applies to
+type walRecord struct {
+	op    byte
+	index uint32
+	tag   uint32
+}
+
+func (r walRecord) encode(b []byte) {
+	b[0] = r.op
+	binary.LittleEndian.PutUint32(b[1:], r.index)
+	binary.LittleEndian.PutUint32(b[1+bytesPerUint32:], r.tag)
+	binary.LittleEndian.PutUint32(b[1+2*bytesPerUint32:], crc32.ChecksumIEEE(b[:1+2*bytesPerUint32]))
+}
+
+func decodeWalRecord(b []byte) (walRecord, bool) {
+	if binary.LittleEndian.Uint32(b[1+2*bytesPerUint32:]) != crc32.ChecksumIEEE(b[:1+2*bytesPerUint32]) {
+		return walRecord{}, false
+	}
+	r := walRecord{
+		op:    b[0],
+		index: binary.LittleEndian.Uint32(b[1:]),
+		tag:   binary.LittleEndian.Uint32(b[1+bytesPerUint32:]),
+	}
+	return r, r.op == walOpAdd || r.op == walOpDelete
+}
+
+// applyWalRecord replay a logged operation on filter
+func (f *Filter) applyWalRecord(r walRecord) error {
+	switch r.op {
+	case walOpAdd:
+		if !f.victim.used {
+			f.insert(uint(r.index), r.tag)
+		} else if !f.repack(batchItem{index: r.index, tag: r.tag}) {
+			// kicks are random, so the victim may be used earlier than when the operation was logged,
+			// fingerprints are placed again offline, which fails only when the logged filter can't hold them
+			return errors.New("filter is full while replaying log")
+		}
+	case walOpDelete:
+		f.deleteImpl(uint(r.index), r.tag)
+	}
+	return nil
+}
+
+// DurableFilter is a Filter persisted in a snapshot file and a write-ahead log of Add/Delete operations,
+// so it survives process crashes without re-encoding the whole table on each change.
+// Like Filter, it is not safe for concurrent use
+type DurableFilter struct {
+	filter *Filter
+	path   string
+
+	log        *os.File
+	generation uint64
+	// num of records appended since last checkpoint
+	logged             uint
+	checkpointInterval uint
+}
+
+// OpenDurableFilter open the filter persisted at path, creating it with the given parameters when not exist.
+// The log is kept in path + ".wal", and a checkpoint is taken automatically every checkpointInterval
+// logged operations, 0 means only checkpoint when Checkpoint is called
+func OpenDurableFilter(path string, checkpointInterval uint, tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) (*DurableFilter, error) {
+	d := &DurableFilter{
+		path:               path,
+		checkpointInterval: checkpointInterval,
+	}
+
+	snapshot, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		d.filter = NewFilter(tagsPerBucket, bitsPerItem, maxNumKeys, tableType)
+	case err != nil:
+		return nil, err
+	case len(snapshot) < walHeaderSize:
+		return nil, fmt.Errorf("snapshot %s is too short", path)
+	default:
+		d.generation = binary.LittleEndian.Uint64(snapshot)
+		if d.filter, err = DecodeFrom(snapshot[walHeaderSize:]); err != nil {
+			return nil, fmt.Errorf("decode snapshot %s: %v", path, err)
+		}
+	}
+
+	if err := d.replay(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DurableFilter) logPath() string {
+	return d.path + ".wal"
+}
+
+// replay apply the log belonging to current snapshot generation and open it for appending,
+// a torn or corrupted last record left by a crash is truncated, while a corrupted record followed by
+// others is an error, since dropping the valid ones after it would lose logged operations
+func (d *DurableFilter) replay() error {
+	log, err := os.OpenFile(d.logPath(), os.O_RDWR, 0644)
+	if os.IsNotExist(err) {
+		return d.resetLog()
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(log)
+	if err != nil {
+		log.Close()
+		return err
+	}
+	if len(data) < walHeaderSize || binary.LittleEndian.Uint64(data) != d.generation {
+		// log is left by an unfinished checkpoint whose snapshot already holds all the operations
+		log.Close()
+		return d.resetLog()
+	}
+
+	end := walHeaderSize
+	for ; end+walRecordSize <= len(data); end += walRecordSize {
+		r, ok := decodeWalRecord(data[end : end+walRecordSize])
+		if !ok {
+			if end+walRecordSize < len(data) {
+				log.Close()
+				return fmt.Errorf("log %s is corrupted at offset %d", d.logPath(), end)
+			}
+			break
+		}
+		if err := d.filter.applyWalRecord(r); err != nil {
+			log.Close()
+			return err
+		}
+		d.logged++
+	}
+	if end != len(data) {
+		if err := log.Truncate(int64(end)); err != nil {
+			log.Close()
+			return err
+		}
+	}
+	if _, err := log.Seek(int64(end), io.SeekStart); err != nil {
+		log.Close()
+		return err
+	}
+	d.log = log
+	return nil
+}
+
+// resetLog atomically replace the log with an empty one of current generation
+func (d *DurableFilter) resetLog() error {
+	var header [walHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[:], d.generation)
+	log, err := writeFileAtomic(d.logPath(), bytes.NewReader(header[:]))
+	if err != nil {
+		return err
+	}
+	if _, err := log.Seek(0, io.SeekEnd); err != nil {
+		log.Close()
+		return err
+	}
+	if d.log != nil {
+		d.log.Close()
+	}
+	d.log = log
+	d.logged = 0
+	return nil
+}
+
+// writeFileAtomic write r into a temporary file, sync and rename it to path, return the opened file
+func writeFileAtomic(path string, r io.Reader) (*os.File, error) {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, r); err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(path))
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	return file, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (d *DurableFilter) append(r walRecord) error {
+	var b [walRecordSize]byte
+	r.encode(b[:])
+	if _, err := d.log.Write(b[:]); err != nil {
+		return err
+	}
+	d.logged++
+	return nil
+}
+
+func (d *DurableFilter) maybeCheckpoint() error {
+	if d.checkpointInterval > 0 && d.logged >= d.checkpointInterval {
+		return d.Checkpoint()
+	}
+	return nil
+}
+
+// Checkpoint write the whole filter into a new snapshot and start an empty log
+func (d *DurableFilter) Checkpoint() error {
+	var header [walHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[:], d.generation+1)
+	filterReader, _ := d.filter.EncodeReader()
+	snapshot, err := writeFileAtomic(d.path, io.MultiReader(bytes.NewReader(header[:]), filterReader))
+	if err != nil {
+		return err
+	}
+	snapshot.Close()
+	d.generation++
+	return d.resetLog()
+}
+
+// Add add an item into filter and log it, return false when filter is full
+func (d *DurableFilter) Add(item []byte) (bool, error) {
+	if d.filter.victim.used {
+		d.filter.rejectInsert()
+		return false, nil
+	}
+	if d.filter.tooLong(len(item)) {
+		return false, ErrItemTooLong
+	}
+	i, tag := d.filter.generateIndexTagHash(item)
+	if err := d.append(walRecord{op: walOpAdd, index: uint32(i), tag: tag}); err != nil {
+		return false, err
+	}
+	d.filter.insert(i, tag)
+	return true, d.maybeCheckpoint()
+}
+
+// AddUnique add an item into filter, return false when filter already contains it or filter is full
+func (d *DurableFilter) AddUnique(item []byte) (bool, error) {
+	if d.filter.Contain(item) {
+		return false, nil
+	}
+	return d.Add(item)
+}
+
+// Contain return if filter contains an item
+func (d *DurableFilter) Contain(item []byte) bool {
+	return d.filter.Contain(item)
+}
+
+// Delete delete item from filter and log it, return false when item not exist
+func (d *DurableFilter) Delete(item []byte) (bool, error) {
+	if d.filter.tooLong(len(item)) {
+		return false, ErrItemTooLong
+	}
+	i, tag := d.filter.generateIndexTagHash(item)
+	if !d.filter.deleteImpl(i, tag) {
+		return false, nil
+	}
+	if err := d.append(walRecord{op: walOpDelete, index: uint32(i), tag: tag}); err != nil {
+		// put the tag back, so filter keeps matching the log
+		d.filter.addImpl(i, tag)
+		return false, err
+	}
+	return true, d.maybeCheckpoint()
+}
+
+// Size return num of items that filter store
+func (d *DurableFilter) Size() uint {
+	return d.filter.Size()
+}
+
+// LoadFactor return current filter's loadFactor
+func (d *DurableFilter) LoadFactor() float64 {
+	return d.filter.LoadFactor()
+}
+
+// Info return filter's detail info
+func (d *DurableFilter) Info() string {
+	return d.filter.Info()
+}
+
+// Sync flush the log to stable storage, logged operations already survive a process crash without it
+func (d *DurableFilter) Sync() error {
+	return d.log.Sync()
+}
+
+// Close close the log, the filter must not be used after Close
+func (d *DurableFilter) Close() error {
+	return d.log.Close()
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/18 22:40
+ */
+
+package cuckoo
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDurableFilter(t *testing.T) {
+	for _, table := range testTableType {
+		path := filepath.Join(t.TempDir(), "filter")
+		df, err := OpenDurableFilter(path, 0, 4, 9, 10000, table)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		a := make([][]byte, 0)
+		for i := 0; i < 1000; i++ {
+			item := make([]byte, 32)
+			_, _ = io.ReadFull(rand.Reader, item)
+			if ok, err := df.Add(item); !ok || err != nil {
+				t.Fatalf("Expected add ok, err %v", err)
+			}
+			a = append(a, item)
+			if i == 500 {
+				if err := df.Checkpoint(); err != nil {
+					t.Fatalf("err %v", err)
+				}
+			}
+		}
+		for _, v := range a[:100] {
+			if ok, err := df.Delete(v); !ok || err != nil {
+				t.Fatalf("Expected delete ok, err %v", err)
+			}
+		}
+		_ = df.Close()
+
+		// simulate a crash in the middle of appending a record
+		log, _ := os.OpenFile(path+".wal", os.O_WRONLY|os.O_APPEND, 0644)
+		_, _ = log.Write([]byte{walOpAdd, 1, 2, 3})
+		_ = log.Close()
+
+		df, err = OpenDurableFilter(path, 300, 4, 9, 10000, table)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		if df.Size() != 900 {
+			t.Fatalf("Expected size 900 after replay, instead %d, table type %v", df.Size(), table)
+		}
+		for _, v := range a[100:] {
+			if !df.Contain(v) {
+				t.Fatalf("Expected contain after replay, table type %v", table)
+			}
+		}
+
+		// trigger automatic checkpoints
+		for _, v := range a[100:500] {
+			if ok, err := df.Delete(v); !ok || err != nil {
+				t.Fatalf("Expected delete ok, err %v", err)
+			}
+		}
+		_ = df.Close()
+		if fi, err := os.Stat(path + ".wal"); err != nil || fi.Size() >= walHeaderSize+300*walRecordSize {
+			t.Fatalf("Expected log to be truncated by checkpoint, err %v", err)
+		}
+
+		df, err = OpenDurableFilter(path, 0, 4, 9, 10000, table)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		if df.Size() != 500 {
+			t.Fatalf("Expected size 500 after reopen, instead %d, table type %v", df.Size(), table)
+		}
+		for _, v := range a[500:] {
+			if !df.Contain(v) {
+				t.Fatalf("Expected contain after reopen, table type %v", table)
+			}
+		}
+		_ = df.Close()
+	}
+}
+
+func TestDurableFilterReplayFull(t *testing.T) {
+	for _, table := range testTableType {
+		path := filepath.Join(t.TempDir(), "filter")
+		df, err := OpenDurableFilter(path, 0, 4, 12, 1000, table)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		a := make([][]byte, 0)
+		for {
+			item := make([]byte, 32)
+			_, _ = io.ReadFull(rand.Reader, item)
+			if ok, err := df.Add(item); err != nil {
+				t.Fatalf("err %v", err)
+			} else if !ok {
+				break
+			}
+			a = append(a, item)
+		}
+		_ = df.Close()
+
+		// kicks of replay differ from the logged ones, so the victim may be used earlier
+		for i := 0; i < 10; i++ {
+			df, err = OpenDurableFilter(path, 0, 4, 12, 1000, table)
+			if err != nil {
+				t.Fatalf("err %v, table type %v", err, table)
+			}
+			if df.Size() != uint(len(a)) {
+				t.Fatalf("Expected size %d after replay, instead %d, table type %v", len(a), df.Size(), table)
+			}
+			for _, v := range a {
+				if !df.Contain(v) {
+					t.Fatalf("Expected contain after replay, table type %v", table)
+				}
+			}
+			_ = df.Close()
+		}
+	}
+}
+
+func TestDurableFilterCorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	df, err := OpenDurableFilter(path, 0, 4, 9, 10000, TableTypeSingle)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	df.filter.EnableCounters()
+	a := make([][]byte, 10)
+	for i := range a {
+		a[i] = make([]byte, 32)
+		_, _ = io.ReadFull(rand.Reader, a[i])
+		if ok, err := df.Add(a[i]); !ok || err != nil {
+			t.Fatalf("Expected add ok, err %v", err)
+		}
+	}
+	if ok, err := df.Delete(a[9]); !ok || err != nil {
+		t.Fatalf("Expected delete ok, err %v", err)
+	}
+	// a delete is not a lookup
+	if s := df.filter.Stats(); s.Lookups != 0 || s.Deletes != 1 {
+		t.Fatalf("Unexpected lookups %d, deletes %d", s.Lookups, s.Deletes)
+	}
+	_ = df.Close()
+
+	data, _ := os.ReadFile(path + ".wal")
+	// a corrupted last record is a torn write, and is truncated
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_ = os.WriteFile(path+".wal", corrupt, 0644)
+	df, err = OpenDurableFilter(path, 0, 4, 9, 10000, TableTypeSingle)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if df.Size() != 10 || !df.Contain(a[9]) {
+		t.Fatalf("Expected last delete dropped, size %d", df.Size())
+	}
+	_ = df.Close()
+
+	// records after a corrupted one are valid, so they can't be dropped
+	corrupt = append([]byte(nil), data...)
+	corrupt[walHeaderSize+5*walRecordSize] ^= 0xff
+	_ = os.WriteFile(path+".wal", corrupt, 0644)
+	if _, err := OpenDurableFilter(path, 0, 4, 9, 10000, TableTypeSingle); err == nil {
+		t.Fatalf("Expected error for corrupted record in the middle of log")
+	}
+}
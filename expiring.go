@@ -0,0 +1,294 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/20 10:00
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ttl + stamp bits
+const expiringHeaderSize = bytesPerUint64 + 1
+
+// ExpiringFilter is a filter whose items expire individually. Each slot of its table carries
+// a stamp of stampBits bits of coarse time beside the tag, which moves with the tag when it is kicked out.
+// Time is counted in ticks of ttl/(2^(stampBits-1)-1), an item is contained for at least ttl and at most
+// ttl plus a tick after it is added, expired slots are treated as empty by Contain and Delete.
+// Expired slots are cleared by Sweep, which should be called at least once every ttl, since stamps wrap around
+// and items not swept in time come alive again. Before that they still count in Size and occupy their slots.
+// The victim carries no stamp, it never expires until it is reinserted. It is not safe for concurrent use
+type ExpiringFilter struct {
+	filter *Filter
+	table  *stampedTable
+	ttl    time.Duration
+	tick   time.Duration
+	now    func() time.Time
+}
+
+// NewExpiringFilter return an ExpiringFilter keeping items for ttl, now is the clock, which is time.Now when nil.
+// bitsPerItem is bits of tag excluding stamp, see NewFilter for the other parameters
+func NewExpiringFilter(ttl time.Duration, stampBits uint, now func() time.Time, tagsPerBucket, bitsPerItem, maxNumKeys uint) (*ExpiringFilter, error) {
+	if err := (Config{TagsPerBucket: tagsPerBucket, BitsPerItem: bitsPerItem, TableType: TableTypeSingle}).Validate(); err != nil {
+		return nil, err
+	}
+	if stampBits < 2 || bitsPerItem+stampBits > 32 {
+		return nil, fmt.Errorf("stamp bits should be within [2, %d] but got %d", 32-bitsPerItem, stampBits)
+	}
+	f := NewFilter(tagsPerBucket, bitsPerItem+stampBits, maxNumKeys, TableTypeSingle)
+	return newExpiringFilter(f, ttl, stampBits, now)
+}
+
+func newExpiringFilter(f *Filter, ttl time.Duration, stampBits uint, now func() time.Time) (*ExpiringFilter, error) {
+	t, ok := f.table.(*SingleTable)
+	if !ok {
+		return nil, errors.New("expiring filter requires single table")
+	}
+	maxAge := uint32(1)<<(stampBits-1) - 1
+	if ttl < time.Duration(maxAge) {
+		return nil, fmt.Errorf("ttl should be at least %v but got %v", time.Duration(maxAge), ttl)
+	}
+	if now == nil {
+		now = time.Now
+	}
+	st := &stampedTable{
+		SingleTable: *t,
+		stamps: slotStamps{
+			bits:   stampBits,
+			fpMask: t.tagMask >> stampBits,
+			mask:   (1 << stampBits) - 1,
+			maxAge: maxAge,
+		},
+	}
+	f.table = st
+	return &ExpiringFilter{filter: f, table: st, ttl: ttl, tick: ttl / time.Duration(maxAge), now: now}, nil
+}
+
+// stampedTable is a SingleTable whose slots are tag | stamp<<(bitsPerTag-stampBits),
+// slots older than maxAge stamps are read as empty
+type stampedTable struct {
+	SingleTable
+	stamps slotStamps
+}
+
+// slotStamps is the age field of slots
+type slotStamps struct {
+	bits   uint
+	fpMask uint32
+	mask   uint32
+	// current is the stamp written with new tags, slots older than maxAge stamps are expired
+	current uint32
+	maxAge  uint32
+	// carry is the stamp of the tag being inserted, which is the stamp of the last kicked out tag during kicks
+	carry uint32
+}
+
+// BitsPerItem return bits of tag excluding stamp
+func (t *stampedTable) BitsPerItem() uint {
+	return t.bitsPerTag - t.stamps.bits
+}
+
+func (t *stampedTable) expired(slot uint32) bool {
+	return (t.stamps.current-slot>>t.BitsPerItem())&t.stamps.mask > t.stamps.maxAge
+}
+
+// readLive read tag from bucket(i,j) without stamp, expired tags are read as 0
+func (t *stampedTable) readLive(i, j uint) uint32 {
+	slot := t.ReadTag(i, j)
+	if t.expired(slot) {
+		return 0
+	}
+	return slot & t.stamps.fpMask
+}
+
+// sweep clear expired slots, return num of them
+func (t *stampedTable) sweep() uint {
+	var n uint
+	for i := uint(0); i < t.numBuckets; i++ {
+		for j := uint(0); j < t.kTagsPerBucket; j++ {
+			if slot := t.ReadTag(i, j); slot&t.stamps.fpMask != 0 && t.expired(slot) {
+				t.WriteTag(i, j, 0)
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// ReadTagsFromBucket read all live tags of bucket i into tags, empty and expired slots are read as 0
+func (t *stampedTable) ReadTagsFromBucket(i uint, tags []uint32) {
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		tags[j] = t.readLive(i, j)
+	}
+}
+
+// FindTagInBucket find if live tag in bucket i
+func (t *stampedTable) FindTagInBucket(i uint, tag uint32) bool {
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		if t.readLive(i, j) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTagInBuckets find if live tag in bucket i1 i2
+func (t *stampedTable) FindTagInBuckets(i1, i2 uint, tag uint32) bool {
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		if t.readLive(i1, j) == tag || t.readLive(i2, j) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteTagFromBucket delete live tag from bucket i
+func (t *stampedTable) DeleteTagFromBucket(i uint, tag uint32) bool {
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		if t.readLive(i, j) == tag {
+			t.WriteTag(i, j, 0)
+			return true
+		}
+	}
+	return false
+}
+
+// InsertTagToBucket insert tag with its stamp, a kicked out tag keeps its stamp when it is inserted next.
+// Expired slots are not reused until swept, so num of items is kept by the filter
+func (t *stampedTable) InsertTagToBucket(i uint, tag uint32, kickOut bool, oldTag *uint32) bool {
+	if !kickOut {
+		t.stamps.carry = t.stamps.current
+	}
+	slot := tag | t.stamps.carry<<t.BitsPerItem()
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		if t.ReadTag(i, j)&t.stamps.fpMask == 0 {
+			t.WriteTag(i, j, slot)
+			return true
+		}
+	}
+	if kickOut {
+		r := uint(rand.Int31()) % t.kTagsPerBucket
+		old := t.ReadTag(i, r)
+		*oldTag = old & t.stamps.fpMask
+		t.stamps.carry = old >> t.BitsPerItem()
+		t.WriteTag(i, r, slot)
+	}
+	return false
+}
+
+func (t *stampedTable) clone() table {
+	nt := *t
+	nt.SingleTable = *t.SingleTable.clone().(*SingleTable)
+	return &nt
+}
+
+// Info return table's info
+func (t *stampedTable) Info() string {
+	return fmt.Sprintf("SingleHashtable with tag size: %v bits \n"+
+		"\t\tStamp size: %v bits \n"+
+		"\t\tAssociativity: %v \n"+
+		"\t\tTotal # of rows: %v\n"+
+		"\t\tTotal # slots: %v\n",
+		t.BitsPerItem(), t.stamps.bits, t.kTagsPerBucket, t.numBuckets, t.SizeInTags())
+}
+
+// advance set stamp written and checked by table to the one of now
+func (e *ExpiringFilter) advance(now time.Time) {
+	e.table.stamps.current = uint32(now.UnixNano()/int64(e.tick)) & e.table.stamps.mask
+}
+
+// Add add an item into filter, return false when filter is full
+func (e *ExpiringFilter) Add(item []byte) bool {
+	e.advance(e.now())
+	return e.filter.Add(item)
+}
+
+// AddUnique add an item into filter, return false when filter already contains it or filter is full
+func (e *ExpiringFilter) AddUnique(item []byte) bool {
+	e.advance(e.now())
+	return e.filter.AddUnique(item)
+}
+
+// Contain return if filter contains an item not expired
+func (e *ExpiringFilter) Contain(item []byte) bool {
+	e.advance(e.now())
+	return e.filter.Contain(item)
+}
+
+// Delete delete item from filter, return false when item not exist or expired
+func (e *ExpiringFilter) Delete(item []byte) bool {
+	e.advance(e.now())
+	return e.filter.Delete(item)
+}
+
+// Sweep clear slots expired at now, return num of them. The victim is reinserted if any slot is cleared
+func (e *ExpiringFilter) Sweep(now time.Time) uint {
+	e.advance(now)
+	f := e.filter
+	n := e.table.sweep()
+	f.numItems -= n
+	if n > 0 && f.victim.used {
+		f.victim.used = false
+		f.addImpl(f.victim.index, f.victim.tag)
+	}
+	return n
+}
+
+// Size return num of items that filter store, including expired ones not swept yet
+func (e *ExpiringFilter) Size() uint {
+	return e.filter.Size()
+}
+
+// LoadFactor return current filter's loadFactor
+func (e *ExpiringFilter) LoadFactor() float64 {
+	return e.filter.LoadFactor()
+}
+
+// TTL return how long items are kept
+func (e *ExpiringFilter) TTL() time.Duration {
+	return e.ttl
+}
+
+// Reset reset the filter
+func (e *ExpiringFilter) Reset() {
+	e.filter.Reset()
+}
+
+// Info return filter's detail info
+func (e *ExpiringFilter) Info() string {
+	return e.filter.Info()
+}
+
+// Encode returns a byte slice representing ttl, stamp bits and the filter
+func (e *ExpiringFilter) Encode() ([]byte, error) {
+	b, err := e.filter.Encode()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, expiringHeaderSize, expiringHeaderSize+len(b))
+	binary.LittleEndian.PutUint64(buf, uint64(e.ttl))
+	buf[bytesPerUint64] = byte(e.table.stamps.bits)
+	return append(buf, b...), nil
+}
+
+// DecodeExpiringFilter returns an ExpiringFilter from bytes of Encode with clock now, which is time.Now when nil
+func DecodeExpiringFilter(b []byte, now func() time.Time) (*ExpiringFilter, error) {
+	if len(b) < expiringHeaderSize {
+		return nil, errors.New("unexpected bytes length")
+	}
+	ttl := time.Duration(binary.LittleEndian.Uint64(b))
+	stampBits := uint(b[bytesPerUint64])
+	f, err := Decode(b[expiringHeaderSize:])
+	if err != nil {
+		return nil, err
+	}
+	if stampBits < 2 || stampBits+2 > f.table.BitsPerItem() {
+		return nil, fmt.Errorf("invalid stamp bits %d", stampBits)
+	}
+	return newExpiringFilter(f, ttl, stampBits, now)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/20 10:00
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestExpiringFilter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	// 7 ticks of 10s
+	e, err := NewExpiringFilter(70*time.Second, 4, clock, 4, 12, 3700)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	e.filter.EnableCounters()
+	if _, err := NewExpiringFilter(time.Minute, 1, clock, 4, 12, 4000); err == nil {
+		t.Fatalf("Expected error for too few stamp bits")
+	}
+
+	// fill to a high load, so that tags of both groups are kicked around
+	item := make([]byte, 8)
+	var old, young uint
+	for i := uint64(0); i < 3700; i++ {
+		if i == 1800 {
+			now = now.Add(50 * time.Second)
+		}
+		binary.LittleEndian.PutUint64(item, uint64(i))
+		if !e.Add(item) {
+			break
+		}
+		if i < 1800 {
+			old++
+		} else {
+			young++
+		}
+	}
+	if e.table.stamps.current == 0 || e.filter.counters.kicks == 0 {
+		t.Fatalf("Expected kicks with stamps")
+	}
+
+	contained := func(from, to uint) (n uint) {
+		for i := from; i < to; i++ {
+			binary.LittleEndian.PutUint64(item, uint64(i))
+			if e.Contain(item) {
+				n++
+			}
+		}
+		return
+	}
+	now = now.Add(15 * time.Second)
+	if contained(0, old) != old || contained(1800, 1800+young) != young {
+		t.Fatalf("Expected all items contained within ttl")
+	}
+
+	// old items expire, young ones keep their stamps after kicks
+	now = now.Add(20 * time.Second)
+	if n := contained(0, old); n > old/50 {
+		t.Fatalf("Expected old items expired, instead %d of %d contained", n, old)
+	}
+	if contained(1800, 1800+young) != young {
+		t.Fatalf("Expected young items contained")
+	}
+	binary.LittleEndian.PutUint64(item, 0)
+	if e.Delete(item) {
+		t.Fatalf("Expected expired item not deleted")
+	}
+
+	b, err := e.Encode()
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	size := e.Size()
+	if n := e.Sweep(now); n < old-old/50 || e.Size() != size-n || e.filter.StashSize() != 0 {
+		t.Fatalf("Expected old items swept, instead %d of %d, size %d", n, old, e.Size())
+	}
+	if contained(1800, 1800+young) != young || !e.Add(item) || !e.Contain(item) {
+		t.Fatalf("Expected young items contained and room for new items after sweep")
+	}
+
+	decoded, err := DecodeExpiringFilter(b, clock)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if decoded.TTL() != e.TTL() || decoded.Size() != size {
+		t.Fatalf("Expected decoded size %d, instead %d", size, decoded.Size())
+	}
+	now = now.Add(70 * time.Second)
+	if n := decoded.Sweep(now); n != size {
+		t.Fatalf("Expected all items swept, instead %d of %d", n, size)
+	}
+	if decoded.Size() != 0 {
+		t.Fatalf("Expected empty filter after sweep")
+	}
+}
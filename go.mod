@@ -1,5 +1,5 @@
 module github.com/linvon/cuckoo-filter
 
-go 1.14
+go 1.18
 
 require github.com/dgryski/go-metro v0.0.0-20200812162917-85c65e2d0165
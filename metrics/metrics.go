@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 16:00
+ */
+
+// Package metrics publish status of cuckoo filters via expvar and in Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+// Source provide stats of a filter, such as *cuckoo.Filter and *cuckoo.SyncFilter, whose counters are published
+// only after EnableCounters is called. Summary is called when metrics are collected, so it must be safe to call
+// concurrently with operations on the filter, a Filter used by multiple goroutines need to be guarded by its owner.
+// Occupancy is not collected since it reads every bucket
+type Source interface {
+	Summary() cuckoo.Stats
+}
+
+// SourceFunc adapt a function to Source
+type SourceFunc func() cuckoo.Stats
+
+// Summary call fn
+func (fn SourceFunc) Summary() cuckoo.Stats {
+	return fn()
+}
+
+// Registry is a set of named filters, it is a http.Handler serving their metrics in Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	sources map[string]Source
+}
+
+// NewRegistry return an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		sources: make(map[string]Source),
+	}
+}
+
+// Register add a filter with name, which is the value of label "filter" of its metrics.
+// A filter registered with the same name is replaced
+func (r *Registry) Register(name string, s Source) {
+	r.mu.Lock()
+	r.sources[name] = s
+	r.mu.Unlock()
+}
+
+// Unregister remove a filter with name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.sources, name)
+	r.mu.Unlock()
+}
+
+// Snapshot return stats of all filters keyed by name
+func (r *Registry) Snapshot() map[string]cuckoo.Stats {
+	r.mu.Lock()
+	sources := make(map[string]Source, len(r.sources))
+	for name, s := range r.sources {
+		sources[name] = s
+	}
+	r.mu.Unlock()
+
+	stats := make(map[string]cuckoo.Stats, len(sources))
+	for name, s := range sources {
+		stats[name] = s.Summary()
+	}
+	return stats
+}
+
+// Publish export stats of all filters as expvar variable with name, in the form of
+// {"filter name": Stats}. Like expvar.Publish, it panics if name is already used
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return r.Snapshot()
+	}))
+}
+
+// ServeHTTP write metrics of all filters in Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WritePrometheus(w)
+}
+
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(s *cuckoo.Stats) float64
+}
+
+var metrics = []metric{
+	{"cuckoo_filter_items", "Num of items stored in filter.", "gauge",
+		func(s *cuckoo.Stats) float64 { return float64(s.Items) }},
+	{"cuckoo_filter_slots", "Num of slots of filter's table.", "gauge",
+		func(s *cuckoo.Stats) float64 { return float64(s.Slots) }},
+	{"cuckoo_filter_load_factor", "Ratio of items to slots.", "gauge",
+		func(s *cuckoo.Stats) float64 { return s.LoadFactor }},
+	{"cuckoo_filter_victim_used", "1 when an item is kept out of table and Add return false.", "gauge",
+		func(s *cuckoo.Stats) float64 { return boolValue(s.VictimUsed) }},
+	{"cuckoo_filter_size_bytes", "Bytes occupancy of filter's table.", "gauge",
+		func(s *cuckoo.Stats) float64 { return float64(s.SizeInBytes) }},
+	{"cuckoo_filter_inserts_total", "Num of items inserted.", "counter",
+		func(s *cuckoo.Stats) float64 { return float64(s.Inserts) }},
+	{"cuckoo_filter_insert_failures_total", "Num of insertions rejected since filter is full.", "counter",
+		func(s *cuckoo.Stats) float64 { return float64(s.FailedInserts) }},
+	{"cuckoo_filter_deletes_total", "Num of items deleted.", "counter",
+		func(s *cuckoo.Stats) float64 { return float64(s.Deletes) }},
+	{"cuckoo_filter_lookups_total", "Num of membership checks.", "counter",
+		func(s *cuckoo.Stats) float64 { return float64(s.Lookups) }},
+	{"cuckoo_filter_lookup_hits_total", "Num of membership checks returning true.", "counter",
+		func(s *cuckoo.Stats) float64 { return float64(s.LookupHits) }},
+}
+
+const kicksMetric = "cuckoo_filter_kicks_per_insert"
+
+// WritePrometheus write metrics of all filters in Prometheus text format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	stats := r.Snapshot()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, name := range names {
+			s := stats[name]
+			fmt.Fprintf(bw, "%s{filter=\"%s\"} %v\n", m.name, escapeLabel(name), m.value(&s))
+		}
+	}
+
+	fmt.Fprintf(bw, "# HELP %s Num of tags moved to make room for each insertion.\n# TYPE %s histogram\n", kicksMetric, kicksMetric)
+	for _, name := range names {
+		s := stats[name]
+		label := escapeLabel(name)
+		var count uint64
+		for k, n := range s.KickHistogram {
+			count += n
+			// KickHistogram[k] counts kicks in [2^(k-1), 2^k)
+			fmt.Fprintf(bw, "%s_bucket{filter=\"%s\",le=\"%d\"} %d\n", kicksMetric, label, uint64(1)<<k-1, count)
+		}
+		fmt.Fprintf(bw, "%s_bucket{filter=\"%s\",le=\"+Inf\"} %d\n", kicksMetric, label, count)
+		fmt.Fprintf(bw, "%s_sum{filter=\"%s\"} %d\n", kicksMetric, label, s.Kicks)
+		fmt.Fprintf(bw, "%s_count{filter=\"%s\"} %d\n", kicksMetric, label, count)
+	}
+	return bw.Flush()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
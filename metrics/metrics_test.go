@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 16:00
+ */
+
+package metrics
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+func TestRegistry(t *testing.T) {
+	cf := cuckoo.NewFilter(4, 8, 1000, cuckoo.TableTypeSingle)
+	cf.EnableCounters()
+	item := make([]byte, 8)
+	for i := uint64(0); ; i++ {
+		binary.LittleEndian.PutUint64(item, i)
+		if !cf.Add(item) {
+			break
+		}
+	}
+	cf.Contain(item)
+
+	r := NewRegistry()
+	r.Register("users \"a\"", cf)
+	r.Register("empty", cuckoo.NewFilter(4, 8, 1000, cuckoo.TableTypePacked))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	s := cf.Stats()
+	for _, line := range []string{
+		"# TYPE cuckoo_filter_load_factor gauge",
+		`cuckoo_filter_victim_used{filter="users \"a\""} 1`,
+		`cuckoo_filter_insert_failures_total{filter="users \"a\""} 1`,
+		`cuckoo_filter_lookups_total{filter="users \"a\""} 1`,
+		`cuckoo_filter_items{filter="empty"} 0`,
+		"# TYPE cuckoo_filter_kicks_per_insert histogram",
+		`cuckoo_filter_kicks_per_insert_bucket{filter="users \"a\"",le="0"} ` + strconv.FormatUint(s.KickHistogram[0], 10),
+		`cuckoo_filter_kicks_per_insert_sum{filter="users \"a\""} ` + strconv.FormatUint(s.Kicks, 10),
+		`cuckoo_filter_kicks_per_insert_count{filter="empty"} 0`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected line %q in\n%s", line, body)
+		}
+	}
+
+	r.Unregister("empty")
+	// expvar names can't be reused when the test runs repeatedly
+	name := fmt.Sprintf("cuckoo_test_%p", r)
+	r.Publish(name)
+	var published map[string]cuckoo.Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &published); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	// occupancy reads every bucket, it is not collected by scrapes
+	if len(published) != 1 || published["users \"a\""].Items != cf.Size() || published["users \"a\""].Occupancy != nil {
+		t.Errorf("Unexpected expvar %v", published)
+	}
+}
@@ -25,6 +25,7 @@ type PackedTable struct {
 	numBuckets uint
 	buckets    []byte
 	perm       PermEncoding
+	dirty      dirtyPages
 }
 
 // NewPackedTable return a packedTable
@@ -55,14 +56,28 @@ func (p *PackedTable) Init(_, bitsPerTag, num uint, initialBucketsHint []byte) e
 	}
 	p.buckets = buckets
 	p.perm.Init()
+	if p.dirty.enabled() {
+		p.dirty.init(p.numBuckets, 1<<p.dirty.pageShift)
+		p.dirty.markAll()
+	}
 	return nil
 }
 
+// TableType return TableTypePacked
+func (p *PackedTable) TableType() uint {
+	return TableTypePacked
+}
+
 // NumBuckets return num of table buckets
 func (p *PackedTable) NumBuckets() uint {
 	return p.numBuckets
 }
 
+// TagsPerBucket return num of tags that each bucket can store, which is always 4
+func (p *PackedTable) TagsPerBucket() uint {
+	return tagsPerPTable
+}
+
 // SizeInTags return num of tags that table can store
 func (p *PackedTable) SizeInTags() uint {
 	return tagsPerPTable * p.numBuckets
@@ -205,6 +220,13 @@ func (p *PackedTable) ReadBucket(i uint, tags *[tagsPerPTable]uint32) {
 	tags[3] |= uint32(lowBits[3])
 }
 
+// ReadTagsFromBucket read all tags of bucket i into tags, empty slots are read as 0
+func (p *PackedTable) ReadTagsFromBucket(i uint, tags []uint32) {
+	var bucket [tagsPerPTable]uint32
+	p.ReadBucket(i, &bucket)
+	copy(tags, bucket[:])
+}
+
 func (p *PackedTable) readOutBytes(i, pos uint) (uint64, uint64, uint) {
 	rShift := (p.kBitsPerBucket * i) & (bitsPerByte - 1)
 	// tag is max 32bit, store 31bit per tag, so max occupies 16 bytes
@@ -224,6 +246,7 @@ func (p *PackedTable) readOutBytes(i, pos uint) (uint64, uint64, uint) {
 
 // WriteBucket write tags into bucket i
 func (p *PackedTable) WriteBucket(i uint, tags [tagsPerPTable]uint32) {
+	p.dirty.mark(i)
 	p.sortTags(&tags)
 
 	/* put in direct bits for each tag*/
@@ -385,6 +408,18 @@ func (p *PackedTable) writeInBytes(i, pos uint, codeword uint16, highBits [tagsP
 	return
 }
 
+func (p *PackedTable) touchBucket(i uint) byte {
+	return p.buckets[i*p.kBitsPerBucket>>3]
+}
+
+// FindTagInBucket find if tag in bucket i
+func (p *PackedTable) FindTagInBucket(i uint, tag uint32) bool {
+	var tags [tagsPerPTable]uint32
+	p.ReadBucket(i, &tags)
+
+	return (tags[0] == tag) || (tags[1] == tag) || (tags[2] == tag) || (tags[3] == tag)
+}
+
 // FindTagInBuckets find if tag in bucket i1 i2
 func (p *PackedTable) FindTagInBuckets(i1, i2 uint, tag uint32) bool {
 	var tags1, tags2 [tagsPerPTable]uint32
@@ -435,6 +470,50 @@ func (p *PackedTable) Reset() {
 	for i := range p.buckets {
 		p.buckets[i] = 0
 	}
+	if p.dirty.enabled() {
+		p.dirty.markAll()
+	}
+}
+
+// clone share the read-only permutation tables with p
+func (p *PackedTable) clone() table {
+	np := *p
+	np.buckets = append([]byte(nil), p.buckets...)
+	np.dirty.bits = append([]uint64(nil), p.dirty.bits...)
+	return &np
+}
+
+// EnableDirtyTracking start recording written buckets in pages of bucketsPerPage buckets,
+// bucketsPerPage is rounded up to a power of two
+func (p *PackedTable) EnableDirtyTracking(bucketsPerPage uint) {
+	p.dirty.init(p.numBuckets, bucketsPerPage)
+}
+
+// DisableDirtyTracking stop recording written buckets
+func (p *PackedTable) DisableDirtyTracking() {
+	p.dirty.disable()
+}
+
+// RangeDirty call fn with offset and length of each dirty byte range in the encoded table,
+// the whole table is reported when tracking is disabled, iteration stops when fn return false
+func (p *PackedTable) RangeDirty(fn func(offset, length uint) bool) {
+	if !p.dirty.enabled() {
+		fn(packedTableMetadataSize, p.len)
+		return
+	}
+	p.dirty.rangeDirty(func(first, end uint) bool {
+		start := first * p.kBitsPerBucket / bitsPerByte
+		stop := (end*p.kBitsPerBucket + 7) / bitsPerByte
+		if stop > p.len {
+			stop = p.len
+		}
+		return fn(packedTableMetadataSize+start, stop-start)
+	})
+}
+
+// ClearDirty mark all buckets as clean
+func (p *PackedTable) ClearDirty() {
+	p.dirty.clear()
 }
 
 // Info return table's info
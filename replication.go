@@ -0,0 +1,334 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 22:00
+ */
+
+package cuckoo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+const (
+	frameOp       = 1
+	frameSnapshot = 2
+
+	// type + sequence number
+	frameHeaderSize = 1 + bytesPerUint64
+)
+
+// Primary is a Filter replicated to followers. Every Add/Delete that changes the filter is sent to
+// followers as an operation frame with a sequence number, and the whole filter is sent as a snapshot frame
+// when a follower is attached and periodically, so followers missing frames catch up, see Follower.
+// Frames are written synchronously, a slow writer slows down the primary. It is safe for concurrent use
+type Primary struct {
+	mu               sync.Mutex
+	filter           *Filter
+	seq              uint64
+	snapshotInterval uint
+	sinceSnapshot    uint
+	followers        []io.Writer
+	buf              [frameHeaderSize + walRecordSize]byte
+}
+
+// NewPrimary return a primary of f, a snapshot is sent every snapshotInterval operations, 0 means only on Attach.
+// f should not be used directly afterwards
+func NewPrimary(f *Filter, snapshotInterval uint) *Primary {
+	return &Primary{filter: f, snapshotInterval: snapshotInterval}
+}
+
+// Attach send a snapshot to w, and then following operations.
+// w is detached when a later write fails, see Followers
+func (p *Primary) Attach(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.writeSnapshot(w); err != nil {
+		return err
+	}
+	p.followers = append(p.followers, w)
+	return nil
+}
+
+// Detach stop sending frames to w
+func (p *Primary) Detach(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, follower := range p.followers {
+		if follower == w {
+			p.followers = append(p.followers[:i], p.followers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Followers return num of attached writers
+func (p *Primary) Followers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.followers)
+}
+
+// SendSnapshot send a snapshot to all followers now
+func (p *Primary) SendSnapshot() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.broadcastSnapshot()
+}
+
+func (p *Primary) broadcastSnapshot() {
+	p.sinceSnapshot = 0
+	followers := p.followers[:0]
+	for _, w := range p.followers {
+		if p.writeSnapshot(w) == nil {
+			followers = append(followers, w)
+		}
+	}
+	p.followers = followers
+}
+
+// writeSnapshot write a snapshot frame: header, size of encoded filter, encoded filter and its crc32
+func (p *Primary) writeSnapshot(w io.Writer) error {
+	r, size := p.filter.EncodeReader()
+	var header [frameHeaderSize + bytesPerUint64]byte
+	header[0] = frameSnapshot
+	binary.LittleEndian.PutUint64(header[1:], p.seq)
+	binary.LittleEndian.PutUint64(header[frameHeaderSize:], uint64(size))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	crc := crc32.NewIEEE()
+	if _, err := io.Copy(io.MultiWriter(w, crc), r); err != nil {
+		return err
+	}
+	var sum [bytesPerUint32]byte
+	binary.LittleEndian.PutUint32(sum[:], crc.Sum32())
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// log send an operation applied to filter to followers
+func (p *Primary) log(r walRecord) {
+	p.seq++
+	p.buf[0] = frameOp
+	binary.LittleEndian.PutUint64(p.buf[1:], p.seq)
+	r.encode(p.buf[frameHeaderSize:])
+	followers := p.followers[:0]
+	for _, w := range p.followers {
+		if _, err := w.Write(p.buf[:]); err == nil {
+			followers = append(followers, w)
+		}
+	}
+	p.followers = followers
+
+	p.sinceSnapshot++
+	if p.snapshotInterval > 0 && p.sinceSnapshot >= p.snapshotInterval {
+		p.broadcastSnapshot()
+	}
+}
+
+// Add add an item into filter, return false when filter is full
+func (p *Primary) Add(item []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filter.tooLong(len(item)) {
+		return false
+	}
+	i, tag := p.filter.generateIndexTagHash(item)
+	if !p.filter.insert(i, tag) {
+		return false
+	}
+	p.log(walRecord{op: walOpAdd, index: uint32(i), tag: tag})
+	return true
+}
+
+// AddUnique add an item into filter, return false when filter already contains it or filter is full
+func (p *Primary) AddUnique(item []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filter.tooLong(len(item)) {
+		return false
+	}
+	i, tag := p.filter.generateIndexTagHash(item)
+	if p.filter.containImpl(i, tag) || !p.filter.insert(i, tag) {
+		return false
+	}
+	p.log(walRecord{op: walOpAdd, index: uint32(i), tag: tag})
+	return true
+}
+
+// Delete delete item from filter, return false when item not exist
+func (p *Primary) Delete(item []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filter.tooLong(len(item)) {
+		return false
+	}
+	i, tag := p.filter.generateIndexTagHash(item)
+	if !p.filter.deleteImpl(i, tag) {
+		return false
+	}
+	p.log(walRecord{op: walOpDelete, index: uint32(i), tag: tag})
+	return true
+}
+
+// Contain return if filter contains an item
+func (p *Primary) Contain(item []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.filter.Contain(item)
+}
+
+// Size return num of items that filter store
+func (p *Primary) Size() uint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.filter.Size()
+}
+
+// Seq return sequence number of the last operation
+func (p *Primary) Seq() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seq
+}
+
+// Follower is a read replica of a Primary, it serves Contain while applying frames read by Run.
+// When a frame is missing, the follower is out of sync and stops applying operations until next snapshot.
+// It is safe for concurrent use
+type Follower struct {
+	mu     sync.Mutex
+	filter *Filter
+	seq    uint64
+	synced bool
+	gaps   uint64
+}
+
+// NewFollower return a follower without filter, which contains nothing until the first snapshot
+func NewFollower() *Follower {
+	return &Follower{}
+}
+
+// ErrCorruptFrame is returned by Follower.Run when a frame fails checksum or has unknown type
+var ErrCorruptFrame = errors.New("corrupt replication frame")
+
+// Run read and apply frames from r until it returns io.EOF, in which case Run returns nil
+func (f *Follower) Run(r io.Reader) error {
+	var header [frameHeaderSize]byte
+	var record [walRecordSize]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		seq := binary.LittleEndian.Uint64(header[1:])
+		switch header[0] {
+		case frameOp:
+			if _, err := io.ReadFull(r, record[:]); err != nil {
+				return err
+			}
+			rec, ok := decodeWalRecord(record[:])
+			if !ok {
+				return ErrCorruptFrame
+			}
+			f.apply(seq, rec)
+		case frameSnapshot:
+			filter, err := readSnapshot(r)
+			if err != nil {
+				return err
+			}
+			f.mu.Lock()
+			f.filter, f.seq, f.synced = filter, seq, true
+			f.mu.Unlock()
+		default:
+			return fmt.Errorf("%w: type %d", ErrCorruptFrame, header[0])
+		}
+	}
+}
+
+func readSnapshot(r io.Reader) (*Filter, error) {
+	var size [bytesPerUint64]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.LittleEndian.Uint64(size[:])
+	// read incrementally, so a corrupt size fails at EOF instead of allocating
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, err
+	}
+	var crc [bytesPerUint32]byte
+	if _, err := io.ReadFull(r, crc[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(crc[:]) != crc32.ChecksumIEEE(buf.Bytes()) {
+		return nil, ErrCorruptFrame
+	}
+	return DecodeFrom(buf.Bytes())
+}
+
+// apply apply an operation frame, operations after a missing one or one failing to apply
+// are skipped until next snapshot
+func (f *Follower) apply(seq uint64, r walRecord) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.synced || seq <= f.seq {
+		return
+	}
+	if seq != f.seq+1 {
+		f.synced = false
+		f.gaps++
+		return
+	}
+	if err := f.filter.applyWalRecord(r); err != nil {
+		f.synced = false
+		return
+	}
+	f.seq = seq
+}
+
+// Contain return if filter contains an item, false before the first snapshot
+func (f *Follower) Contain(item []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.filter != nil && f.filter.Contain(item)
+}
+
+// Size return num of items that filter store
+func (f *Follower) Size() uint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.filter == nil {
+		return 0
+	}
+	return f.filter.Size()
+}
+
+// Seq return sequence number of the last applied operation or snapshot
+func (f *Follower) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// Synced return false before the first snapshot, and after a missing frame or an operation failing to apply
+// until next snapshot
+func (f *Follower) Synced() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.synced
+}
+
+// Gaps return num of times a missing frame is detected
+func (f *Follower) Gaps() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.gaps
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 22:00
+ */
+
+package cuckoo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestReplication(t *testing.T) {
+	for _, table := range testTableType {
+		p := NewPrimary(NewFilter(4, 9, 10000, table), 300)
+		r, w := io.Pipe()
+		follower := NewFollower()
+		done := make(chan error)
+		go func() { done <- follower.Run(r) }()
+		if err := p.Attach(w); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		a := make([][]byte, 0)
+		for i := 0; i < 1000; i++ {
+			item := make([]byte, 32)
+			_, _ = io.ReadFull(rand.Reader, item)
+			if !p.Add(item) {
+				t.Fatalf("Expected add ok")
+			}
+			a = append(a, item)
+			// serve lookups while applying
+			follower.Contain(item)
+		}
+		for _, v := range a[:100] {
+			if !p.Delete(v) {
+				t.Fatalf("Expected delete ok")
+			}
+		}
+		p.Detach(w)
+		_ = w.Close()
+		if err := <-done; err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if follower.Seq() != p.Seq() || follower.Size() != 900 || !follower.Synced() {
+			t.Fatalf("Expected follower at seq %d with 900 items, instead seq %d, size %d, table type %v",
+				p.Seq(), follower.Seq(), follower.Size(), table)
+		}
+		for _, v := range a[100:] {
+			if !follower.Contain(v) {
+				t.Fatalf("Expected follower contain, table type %v", table)
+			}
+		}
+	}
+}
+
+func TestReplicationGap(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrimary(NewFilter(4, 9, 1000, TableTypePacked), 0)
+	_ = p.Attach(&buf)
+	p.Add([]byte("a"))
+	p.Add([]byte("b"))
+	p.Add([]byte("c"))
+
+	// drop the frame of "b"
+	stream := buf.Bytes()
+	opSize := frameHeaderSize + walRecordSize
+	end := len(stream) - 2*opSize
+	stream = append(stream[:end:end], stream[end+opSize:]...)
+
+	follower := NewFollower()
+	if err := follower.Run(bytes.NewReader(stream)); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if follower.Synced() || follower.Gaps() != 1 || follower.Seq() != 1 || follower.Contain([]byte("c")) {
+		t.Fatalf("Expected gap detected after seq 1, instead synced %v, gaps %d, seq %d",
+			follower.Synced(), follower.Gaps(), follower.Seq())
+	}
+	if !follower.Contain([]byte("a")) {
+		t.Fatalf("Expected contain operations before gap")
+	}
+
+	// catch up by next snapshot
+	buf.Reset()
+	p.SendSnapshot()
+	p.Add([]byte("d"))
+	if err := follower.Run(&buf); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if !follower.Synced() || follower.Seq() != 4 || follower.Size() != 4 {
+		t.Fatalf("Expected synced at seq 4, instead seq %d, size %d", follower.Seq(), follower.Size())
+	}
+
+	stream = append([]byte(nil), stream...)
+	stream[len(stream)-1] ^= 0xff
+	if err := NewFollower().Run(bytes.NewReader(stream)); err != ErrCorruptFrame {
+		t.Fatalf("Expected ErrCorruptFrame, instead %v", err)
+	}
+}
+
+func TestReplicationApplyFailure(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrimary(NewFilter(2, 8, 2, TableTypeSingle), 0)
+	var n int
+	for ; p.Add([]byte{byte(n)}); n++ {
+	}
+	_ = p.Attach(&buf)
+	// an operation the full follower can't apply
+	p.log(walRecord{op: walOpAdd, index: 0, tag: 1})
+
+	follower := NewFollower()
+	if err := follower.Run(&buf); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if follower.Synced() || follower.Seq() != uint64(n) || follower.Size() != uint(n) {
+		t.Fatalf("Expected unsynced at seq %d, instead synced %v, seq %d", n, follower.Synced(), follower.Seq())
+	}
+
+	buf.Reset()
+	p.Delete([]byte{0})
+	p.SendSnapshot()
+	if err := follower.Run(&buf); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if !follower.Synced() || follower.Seq() != p.Seq() || follower.Size() != p.Size() {
+		t.Fatalf("Expected synced by snapshot, instead seq %d, size %d", follower.Seq(), follower.Size())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestReplicationDetach(t *testing.T) {
+	p := NewPrimary(NewFilter(4, 9, 1000, TableTypePacked), 0)
+	if err := p.Attach(failingWriter{}); err == nil {
+		t.Fatalf("Expected attach error")
+	}
+	r, w := io.Pipe()
+	go func() { _, _ = io.Copy(io.Discard, r) }()
+	_ = p.Attach(w)
+	if p.Followers() != 1 {
+		t.Fatalf("Expected 1 follower")
+	}
+	_ = r.Close()
+	if !p.Add([]byte("a")) || p.Followers() != 0 {
+		t.Fatalf("Expected follower detached after write error")
+	}
+}
@@ -0,0 +1,575 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 19:00
+ */
+
+// Package resp serve cuckoo filters over the Redis protocol with the CF.* commands of RedisBloom:
+// CF.RESERVE, CF.ADD, CF.ADDNX, CF.EXISTS, CF.MEXISTS, CF.DEL, CF.COUNT, CF.INFO, CF.SCANDUMP and CF.LOADCHUNK,
+// together with PING, QUIT, DEL and EXISTS.
+//
+// Filters never expand, so EXPANSION and MAXITERATIONS of CF.RESERVE are accepted but ignored,
+// and CF.ADD fails with an error when filter is full.
+// CF.SCANDUMP chunks are an 8 bytes little-endian length followed by the Encode output of filter,
+// they can only be loaded by CF.LOADCHUNK of this package, in order and on one connection
+package resp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+const (
+	// defaultCapacity is capacity of filters created by CF.ADD, like RedisBloom
+	defaultCapacity = 1024
+	// defaultBucketSize is BUCKETSIZE of CF.RESERVE, like RedisBloom
+	defaultBucketSize = 2
+	// dumpHeaderSize is the length prefix of CF.SCANDUMP data
+	dumpHeaderSize = 8
+	// maxChunkSize is max length of a CF.SCANDUMP chunk
+	maxChunkSize = 1 << 20
+	// maxDumpSize is max size of filters being loaded by CF.LOADCHUNK on a connection,
+	// which are buffered until the last chunk
+	maxDumpSize = 1 << 30
+	// DefaultMaxCapacity is the default MaxCapacity, whose filter takes 512MB at most with 32 bits per item
+	DefaultMaxCapacity = 1 << 26
+)
+
+// Server is a RESP server of cuckoo filters
+type Server struct {
+	// BitsPerItem is fingerprint length of created filters, default to 8 like RedisBloom
+	BitsPerItem uint
+	// MaxCapacity limit capacity of CF.RESERVE, which decides memory of filters
+	MaxCapacity uint
+
+	mu        sync.Mutex
+	filters   map[string]*cuckoo.SyncFilter
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer return a server without filters
+func NewServer() *Server {
+	return &Server{
+		BitsPerItem: 8,
+		MaxCapacity: DefaultMaxCapacity,
+		filters:     make(map[string]*cuckoo.SyncFilter),
+		listeners:   make(map[net.Listener]struct{}),
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// ErrServerClosed is returned by Serve after Close
+var ErrServerClosed = errors.New("resp: server closed")
+
+// ListenAndServe listen on TCP address addr and serve connections
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accept connections on l and serve each in a goroutine, it always return a non-nil error
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close close listeners and connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	return nil
+}
+
+// Filter return filter with key
+func (s *Server) Filter(key string) (*cuckoo.SyncFilter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.filters[key]
+	return f, ok
+}
+
+// Set add or replace filter with key
+func (s *Server) Set(key string, f *cuckoo.Filter) {
+	s.mu.Lock()
+	s.filters[key] = guard(f)
+	s.mu.Unlock()
+}
+
+// guard return f guarded for concurrent connections, with counters enabled for stats
+func guard(f *cuckoo.Filter) *cuckoo.SyncFilter {
+	f.EnableCounters()
+	return cuckoo.NewSyncFilter(f)
+}
+
+// dump is a CF.SCANDUMP in progress on a connection
+type dump struct {
+	// data is the filter encoded when dump starts, so later modifications don't tear it
+	data []byte
+	// next is the iterator expected by next CF.SCANDUMP
+	next int64
+}
+
+// load is a CF.LOADCHUNK in progress on a connection
+type load struct {
+	// data is the chunks received so far
+	data []byte
+	// size is the filter size of the first chunk
+	size uint64
+}
+
+type conn struct {
+	s     *Server
+	w     writer
+	dumps map[string]*dump
+	loads map[string]*load
+	// loading is the sum of sizes of loads, bounded by maxDumpSize
+	loading uint64
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+		nc.Close()
+	}()
+
+	r := bufio.NewReader(nc)
+	c := &conn{s: s, w: writer{bufio.NewWriter(nc)}, dumps: make(map[string]*dump), loads: make(map[string]*load)}
+	defer func() {
+		// a command panicking close its connection instead of the whole server
+		if err := recover(); err != nil {
+			c.w.errorMsg(fmt.Sprint("ERR internal error: ", err))
+			c.w.Flush()
+		}
+	}()
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.errorMsg("ERR " + err.Error())
+				c.w.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		quit := c.exec(strings.ToUpper(string(args[0])), args[1:])
+		// flush when no more pipelined commands are buffered
+		if r.Buffered() == 0 || quit {
+			if err := c.w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+var errSyntax = errors.New("ERR syntax error")
+
+func errArgs(cmd string) error {
+	return errors.New("ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command")
+}
+
+// exec run a command and write the reply, return true when connection should be closed
+func (c *conn) exec(cmd string, args [][]byte) bool {
+	var err error
+	switch cmd {
+	case "PING":
+		if len(args) > 0 {
+			c.w.bulk(args[0])
+		} else {
+			c.w.simple("PONG")
+		}
+	case "QUIT":
+		c.w.simple("OK")
+		return true
+	case "DEL":
+		err = c.del(args)
+	case "EXISTS":
+		err = c.exists(args)
+	case "CF.RESERVE":
+		err = c.reserve(args)
+	case "CF.ADD", "CF.ADDNX":
+		err = c.add(cmd, args)
+	case "CF.EXISTS":
+		err = c.contain(cmd, args, false)
+	case "CF.MEXISTS":
+		err = c.contain(cmd, args, true)
+	case "CF.DEL":
+		err = c.delete(args)
+	case "CF.COUNT":
+		err = c.count(args)
+	case "CF.INFO":
+		err = c.info(args)
+	case "CF.SCANDUMP":
+		err = c.scanDump(args)
+	case "CF.LOADCHUNK":
+		err = c.loadChunk(args)
+	default:
+		err = errors.New("ERR unknown command '" + strings.ToLower(cmd) + "'")
+	}
+	if err != nil {
+		c.w.errorMsg(err.Error())
+	}
+	return false
+}
+
+func (c *conn) del(args [][]byte) error {
+	if len(args) == 0 {
+		return errArgs("DEL")
+	}
+	var n int64
+	c.s.mu.Lock()
+	for _, key := range args {
+		if _, ok := c.s.filters[string(key)]; ok {
+			delete(c.s.filters, string(key))
+			n++
+		}
+	}
+	c.s.mu.Unlock()
+	c.w.integer(n)
+	return nil
+}
+
+func (c *conn) exists(args [][]byte) error {
+	if len(args) == 0 {
+		return errArgs("EXISTS")
+	}
+	var n int64
+	for _, key := range args {
+		if _, ok := c.s.Filter(string(key)); ok {
+			n++
+		}
+	}
+	c.w.integer(n)
+	return nil
+}
+
+// CF.RESERVE key capacity [BUCKETSIZE bucketsize] [MAXITERATIONS maxiterations] [EXPANSION expansion]
+func (c *conn) reserve(args [][]byte) error {
+	if len(args) < 2 || len(args)%2 != 0 {
+		return errArgs("CF.RESERVE")
+	}
+	capacity, err := parsePositive(args[1])
+	if err != nil {
+		return errors.New("ERR Bad capacity")
+	}
+	if capacity > c.s.MaxCapacity {
+		return fmt.Errorf("ERR capacity exceeds max capacity %d", c.s.MaxCapacity)
+	}
+	bucketSize := uint(defaultBucketSize)
+	for i := 2; i < len(args); i += 2 {
+		n, err := parsePositive(args[i+1])
+		switch strings.ToUpper(string(args[i])) {
+		case "BUCKETSIZE":
+			if err != nil || n > 255 {
+				return errors.New("ERR Bad bucket size")
+			}
+			bucketSize = n
+		case "MAXITERATIONS":
+			if err != nil {
+				return errors.New("ERR Bad maxIterations")
+			}
+		case "EXPANSION":
+			if _, err := strconv.ParseUint(string(args[i+1]), 10, 32); err != nil {
+				return errors.New("ERR Bad expansion")
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	key := string(args[0])
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if _, ok := c.s.filters[key]; ok {
+		return errors.New("ERR item exists")
+	}
+	c.s.filters[key] = guard(cuckoo.NewFilter(bucketSize, c.s.BitsPerItem, capacity, cuckoo.TableTypeSingle))
+	c.w.simple("OK")
+	return nil
+}
+
+func min(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func parsePositive(b []byte) (uint, error) {
+	n, err := strconv.ParseUint(string(b), 10, 63)
+	if err == nil && n == 0 {
+		err = errSyntax
+	}
+	return uint(n), err
+}
+
+func (c *conn) filter(key []byte) (*cuckoo.SyncFilter, error) {
+	f, ok := c.s.Filter(string(key))
+	if !ok {
+		return nil, errors.New("ERR not found")
+	}
+	return f, nil
+}
+
+// CF.ADD key item, CF.ADDNX key item; the filter is created with default capacity if not exists
+func (c *conn) add(cmd string, args [][]byte) error {
+	if len(args) != 2 {
+		return errArgs(cmd)
+	}
+	key := string(args[0])
+	c.s.mu.Lock()
+	f, ok := c.s.filters[key]
+	if !ok {
+		f = guard(cuckoo.NewFilter(defaultBucketSize, c.s.BitsPerItem, defaultCapacity, cuckoo.TableTypeSingle))
+		c.s.filters[key] = f
+	}
+	c.s.mu.Unlock()
+
+	added, full := true, false
+	f.Do(func(f *cuckoo.Filter) {
+		if cmd == "CF.ADDNX" && f.Contain(args[1]) {
+			added = false
+			return
+		}
+		full = !f.Add(args[1])
+	})
+	if full {
+		return errors.New("ERR Filter is full")
+	}
+	c.w.boolean(added)
+	return nil
+}
+
+// CF.EXISTS key item, CF.MEXISTS key item [item...]; items of not existing filters are not contained
+func (c *conn) contain(cmd string, args [][]byte, multi bool) error {
+	if len(args) < 2 || !multi && len(args) != 2 {
+		return errArgs(cmd)
+	}
+	f, ok := c.s.Filter(string(args[0]))
+	if multi {
+		c.w.array(len(args) - 1)
+	}
+	for _, item := range args[1:] {
+		c.w.boolean(ok && f.Contain(item))
+	}
+	return nil
+}
+
+// CF.DEL key item
+func (c *conn) delete(args [][]byte) error {
+	if len(args) != 2 {
+		return errArgs("CF.DEL")
+	}
+	f, err := c.filter(args[0])
+	if err != nil {
+		return err
+	}
+	c.w.boolean(f.Delete(args[1]))
+	return nil
+}
+
+// CF.COUNT key item
+func (c *conn) count(args [][]byte) error {
+	if len(args) != 2 {
+		return errArgs("CF.COUNT")
+	}
+	var n uint
+	if f, ok := c.s.Filter(string(args[0])); ok {
+		f.Do(func(f *cuckoo.Filter) {
+			n = f.Count(args[1])
+		})
+	}
+	c.w.integer(int64(n))
+	return nil
+}
+
+// CF.INFO key
+func (c *conn) info(args [][]byte) error {
+	if len(args) != 1 {
+		return errArgs("CF.INFO")
+	}
+	f, err := c.filter(args[0])
+	if err != nil {
+		return err
+	}
+	s := f.Summary()
+	fields := []struct {
+		name  string
+		value int64
+	}{
+		{"Size", int64(s.SizeInBytes)},
+		{"Number of buckets", int64(s.NumBuckets)},
+		{"Number of filters", 1},
+		{"Number of items inserted", int64(s.Items)},
+		{"Number of items deleted", int64(s.Deletes)},
+		{"Bucket size", int64(s.TagsPerBucket)},
+		{"Expansion rate", 0},
+		{"Max iterations", 500},
+	}
+	c.w.array(2 * len(fields))
+	for _, field := range fields {
+		c.w.simple(field.name)
+		c.w.integer(field.value)
+	}
+	return nil
+}
+
+// CF.SCANDUMP key iterator, start with iterator 0 and continue with the returned one until it is 0
+func (c *conn) scanDump(args [][]byte) error {
+	if len(args) != 2 {
+		return errArgs("CF.SCANDUMP")
+	}
+	key := string(args[0])
+	iter, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || iter < 0 {
+		return errors.New("ERR Invalid iterator")
+	}
+
+	if iter == 0 {
+		sf, err := c.filter(args[0])
+		if err != nil {
+			return err
+		}
+		data, err := sf.Encode()
+		if err != nil {
+			return errors.New("ERR " + err.Error())
+		}
+		d := &dump{data: data, next: 1 + dumpHeaderSize}
+		var header [dumpHeaderSize]byte
+		binary.LittleEndian.PutUint64(header[:], uint64(len(data)))
+		c.dumps[key] = d
+		c.w.array(2)
+		c.w.integer(d.next)
+		c.w.bulk(header[:])
+		return nil
+	}
+
+	d, ok := c.dumps[key]
+	if !ok || d.next != iter {
+		return errors.New("ERR Invalid iterator")
+	}
+	if len(d.data) == 0 {
+		delete(c.dumps, key)
+		c.w.array(2)
+		c.w.integer(0)
+		c.w.bulk(nil)
+		return nil
+	}
+	chunk := d.data[:min(uint(len(d.data)), maxChunkSize)]
+	d.data = d.data[len(chunk):]
+	d.next += int64(len(chunk))
+	c.w.array(2)
+	c.w.integer(d.next)
+	c.w.bulk(chunk)
+	return nil
+}
+
+// CF.LOADCHUNK key iterator data, with the replies of CF.SCANDUMP in order.
+// The filter is replaced when the last chunk is loaded
+func (c *conn) loadChunk(args [][]byte) error {
+	if len(args) != 3 {
+		return errArgs("CF.LOADCHUNK")
+	}
+	key, data := string(args[0]), args[2]
+	iter, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil || iter <= int64(len(data)) {
+		return errors.New("ERR Invalid iterator")
+	}
+	if len(data) > maxChunkSize {
+		return errors.New("ERR chunk too large")
+	}
+	offset := iter - 1 - int64(len(data))
+
+	ld := c.loads[key]
+	switch {
+	case offset == 0 && len(data) == dumpHeaderSize:
+		// a new load of key replace the one in progress
+		c.dropLoad(key)
+		size := binary.LittleEndian.Uint64(data)
+		if size > maxDumpSize-c.loading {
+			return errors.New("ERR filter too large")
+		}
+		ld = &load{size: size}
+		c.loads[key] = ld
+		c.loading += size
+	case ld == nil || offset != int64(dumpHeaderSize+len(ld.data)):
+		return errors.New("ERR chunk out of order")
+	default:
+		if uint64(len(ld.data)+len(data)) > ld.size {
+			c.dropLoad(key)
+			return errors.New("ERR chunk exceeds filter size")
+		}
+		ld.data = append(ld.data, data...)
+	}
+	if uint64(len(ld.data)) < ld.size {
+		c.w.simple("OK")
+		return nil
+	}
+	c.dropLoad(key)
+	f, err := cuckoo.DecodeFrom(ld.data)
+	if err != nil {
+		return errors.New("ERR " + err.Error())
+	}
+	c.s.Set(key, f)
+	c.w.simple("OK")
+	return nil
+}
+
+// dropLoad forget the load of key in progress
+func (c *conn) dropLoad(key string) {
+	if ld, ok := c.loads[key]; ok {
+		c.loading -= ld.size
+		delete(c.loads, key)
+	}
+}
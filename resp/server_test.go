@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 19:00
+ */
+
+package resp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+// client is a minimal RESP client, replies are decoded into string, int64, nil, replyError or []interface{}
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+type replyError string
+
+func dial(t *testing.T, addr string) *client {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	return &client{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) do(t *testing.T, args ...string) interface{} {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	reply, err := c.read()
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	return reply
+}
+
+func (c *client) read() (interface{}, error) {
+	line, err := readLine(c.r)
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return replyError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, _ := strconv.Atoi(string(line[1:]))
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, _ := strconv.Atoi(string(line[1:]))
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+func expect(t *testing.T, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %#v, instead %#v", want, got)
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- s.Serve(l) }()
+	defer func() {
+		s.Close()
+		if err := <-done; err != ErrServerClosed {
+			t.Errorf("Expected ErrServerClosed, instead %v", err)
+		}
+	}()
+
+	c := dial(t, l.Addr().String())
+	expect(t, c.do(t, "PING"), "PONG")
+	expect(t, c.do(t, "CF.RESERVE", "cf", "1000", "BUCKETSIZE", "4", "MAXITERATIONS", "20", "EXPANSION", "1"), "OK")
+	expect(t, c.do(t, "CF.RESERVE", "cf", "1000"), replyError("ERR item exists"))
+	expect(t, c.do(t, "CF.RESERVE", "bad", "0"), replyError("ERR Bad capacity"))
+	expect(t, c.do(t, "CF.RESERVE", "bad", "1000000000000"), replyError(fmt.Sprintf("ERR capacity exceeds max capacity %d", DefaultMaxCapacity)))
+	expect(t, c.do(t, "CF.ADD", "cf", "a"), int64(1))
+	expect(t, c.do(t, "CF.ADD", "cf", "a"), int64(1))
+	expect(t, c.do(t, "CF.ADDNX", "cf", "a"), int64(0))
+	expect(t, c.do(t, "CF.ADDNX", "cf", "b"), int64(1))
+	expect(t, c.do(t, "CF.COUNT", "cf", "a"), int64(2))
+	expect(t, c.do(t, "CF.EXISTS", "cf", "b"), int64(1))
+	expect(t, c.do(t, "CF.MEXISTS", "cf", "a", "b", "c"), []interface{}{int64(1), int64(1), int64(0)})
+	expect(t, c.do(t, "CF.DEL", "cf", "b"), int64(1))
+	expect(t, c.do(t, "CF.DEL", "cf", "b"), int64(0))
+	expect(t, c.do(t, "CF.DEL", "missing", "b"), replyError("ERR not found"))
+	expect(t, c.do(t, "CF.EXISTS", "missing", "b"), int64(0))
+	expect(t, c.do(t, "CF.INFO", "cf"), []interface{}{
+		"Size", int64(2048), "Number of buckets", int64(512), "Number of filters", int64(1),
+		"Number of items inserted", int64(2), "Number of items deleted", int64(1),
+		"Bucket size", int64(4), "Expansion rate", int64(0), "Max iterations", int64(500),
+	})
+	expect(t, c.do(t, "CF.FOO"), replyError("ERR unknown command 'cf.foo'"))
+	expect(t, c.do(t, "CF.ADD", "cf"), replyError("ERR wrong number of arguments for 'cf.add' command"))
+
+	// CF.ADD create filter with default capacity
+	for i := 0; i < 500; i++ {
+		expect(t, c.do(t, "CF.ADD", "auto", strconv.Itoa(i)), int64(1))
+	}
+
+	// dump and load through another connection, items added during dump are not dumped
+	c2 := dial(t, l.Addr().String())
+	iter := "0"
+	for {
+		reply := c.do(t, "CF.SCANDUMP", "auto", iter).([]interface{})
+		if reply[0].(int64) == 0 {
+			expect(t, reply[1], nil)
+			break
+		}
+		iter = strconv.FormatInt(reply[0].(int64), 10)
+		expect(t, c2.do(t, "CF.LOADCHUNK", "copy", iter, reply[1].(string)), "OK")
+		expect(t, c.do(t, "CF.ADD", "auto", "late"+iter), int64(1))
+	}
+	for i := 0; i < 500; i++ {
+		expect(t, c2.do(t, "CF.EXISTS", "copy", strconv.Itoa(i)), int64(1))
+	}
+	expect(t, c2.do(t, "CF.INFO", "copy").([]interface{})[7], int64(500))
+	expect(t, c2.do(t, "CF.LOADCHUNK", "copy", "100", "abc"), replyError("ERR chunk out of order"))
+
+	// invalid filters are rejected, instead of crashing later commands
+	var header [dumpHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[:], 1<<40)
+	expect(t, c2.do(t, "CF.LOADCHUNK", "bad", "9", string(header[:])), replyError("ERR filter too large"))
+	bad, _ := cuckoo.NewFilter(2, 8, 100, cuckoo.TableTypeSingle).Encode()
+	binary.LittleEndian.PutUint32(bad[16:], 0)
+	binary.LittleEndian.PutUint64(header[:], uint64(len(bad)))
+	expect(t, c2.do(t, "CF.LOADCHUNK", "bad", "9", string(header[:])), "OK")
+	expect(t, c2.do(t, "CF.LOADCHUNK", "bad", strconv.Itoa(9+len(bad)), string(bad)),
+		replyError("ERR invalid num of buckets 0 for table type 0"))
+	expect(t, c2.do(t, "CF.EXISTS", "bad", "a"), int64(0))
+	expect(t, c2.do(t, "CF.SCANDUMP", "auto", "5"), replyError("ERR Invalid iterator"))
+
+	// loads are kept by connection, and bounded by maxDumpSize in total
+	binary.LittleEndian.PutUint64(header[:], maxDumpSize)
+	expect(t, c2.do(t, "CF.LOADCHUNK", "big", "9", string(header[:])), "OK")
+	binary.LittleEndian.PutUint64(header[:], 1)
+	expect(t, c2.do(t, "CF.LOADCHUNK", "small", "9", string(header[:])), replyError("ERR filter too large"))
+	c3 := dial(t, l.Addr().String())
+	expect(t, c3.do(t, "CF.LOADCHUNK", "small", "9", string(header[:])), "OK")
+	expect(t, c3.do(t, "CF.LOADCHUNK", "big", "10", "a"), replyError("ERR chunk out of order"))
+	expect(t, c3.do(t, "QUIT"), "OK")
+
+	expect(t, c2.do(t, "EXISTS", "copy", "auto", "none"), int64(2))
+	expect(t, c2.do(t, "DEL", "copy", "none"), int64(1))
+	expect(t, c2.do(t, "QUIT"), "OK")
+
+	// inline commands
+	if _, err := io.WriteString(c.conn, "CF.EXISTS auto 1\r\n"); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	reply, _ := c.read()
+	expect(t, reply, int64(1))
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 18:00
+ */
+
+// Package server expose named cuckoo filters over HTTP.
+//
+// Routes, where keys are JSON strings:
+//
+//	GET    /filters                   list names of filters
+//	POST   /filters/{name}            create a filter, body {"tagsPerBucket":4,"bitsPerItem":9,"maxNumKeys":1000,"tableType":"single"}
+//	DELETE /filters/{name}            drop a filter
+//	POST   /filters/{name}/add        body {"key":"a"} or {"keys":["a","b"]}, respond {"result":true} or {"results":[true,false]}
+//	POST   /filters/{name}/addunique  same as add, false when key is already contained
+//	POST   /filters/{name}/contain    same as add
+//	POST   /filters/{name}/delete     same as add
+//	GET    /filters/{name}/stats      respond cuckoo.Stats
+//	GET    /filters/{name}/snapshot   respond the Encode output
+//	PUT    /filters/{name}/snapshot   create or replace a filter with the Encode output in body
+//
+// Errors are responded as {"error":"message"} with a non-2xx status
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+// Server is a http.Handler managing named filters
+type Server struct {
+	mu      sync.RWMutex
+	filters map[string]*cuckoo.SyncFilter
+	// MaxBodyBytes limit size of request bodies, including uploaded snapshots
+	MaxBodyBytes int64
+	// MaxNumKeys limit maxNumKeys of created filters, which decides memory of them
+	MaxNumKeys uint
+}
+
+const (
+	// DefaultMaxBodyBytes is the default MaxBodyBytes
+	DefaultMaxBodyBytes = 64 << 20
+	// DefaultMaxNumKeys is the default MaxNumKeys, whose filter takes 512MB at most with 32 bits per item
+	DefaultMaxNumKeys = 1 << 26
+)
+
+// New return a server without filters
+func New() *Server {
+	return &Server{
+		filters:      make(map[string]*cuckoo.SyncFilter),
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		MaxNumKeys:   DefaultMaxNumKeys,
+	}
+}
+
+// Filter return filter with name
+func (s *Server) Filter(name string) (*cuckoo.SyncFilter, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.filters[name]
+	return f, ok
+}
+
+// Set add or replace filter with name
+func (s *Server) Set(name string, f *cuckoo.Filter) {
+	s.mu.Lock()
+	s.filters[name] = guard(f)
+	s.mu.Unlock()
+}
+
+// guard return f guarded for concurrent requests, with counters enabled for stats
+func guard(f *cuckoo.Filter) *cuckoo.SyncFilter {
+	f.EnableCounters()
+	return cuckoo.NewSyncFilter(f)
+}
+
+// Names return names of all filters in order
+func (s *Server) Names() []string {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.filters))
+	for name := range s.filters {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// CreateRequest is the body to create a filter, see cuckoo.NewFilter
+type CreateRequest struct {
+	TagsPerBucket uint `json:"tagsPerBucket"`
+	BitsPerItem   uint `json:"bitsPerItem"`
+	MaxNumKeys    uint `json:"maxNumKeys"`
+	// TableType is "single", "packed" or "vacuum"
+	TableType string `json:"tableType"`
+}
+
+// KeysRequest is the body of key operations, either Key or Keys is set
+type KeysRequest struct {
+	Key  *string  `json:"key,omitempty"`
+	Keys []string `json:"keys,omitempty"`
+}
+
+// KeysResponse is the response of key operations, Result is set for Key and Results for Keys
+type KeysResponse struct {
+	Result  *bool  `json:"result,omitempty"`
+	Results []bool `json:"results,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &httpError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP route requests, see package doc
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	if err := s.route(w, r); err != nil {
+		code := http.StatusInternalServerError
+		var he *httpError
+		if errors.As(err, &he) {
+			code = he.code
+		}
+		writeJSON(w, code, errorResponse{Error: err.Error()})
+	}
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) error {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "filters" || len(parts) > 3 {
+		return errorf(http.StatusNotFound, "not found")
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			return errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		}
+		writeJSON(w, http.StatusOK, s.Names())
+		return nil
+	}
+
+	name := parts[1]
+	if name == "" {
+		return errorf(http.StatusNotFound, "empty filter name")
+	}
+	op := ""
+	if len(parts) == 3 {
+		op = parts[2]
+	}
+	switch {
+	case op == "" && r.Method == http.MethodPost:
+		return s.create(w, r, name)
+	case op == "" && r.Method == http.MethodDelete:
+		return s.drop(w, name)
+	case op == "snapshot" && r.Method == http.MethodPut:
+		return s.upload(w, r, name)
+	}
+
+	f, ok := s.Filter(name)
+	if !ok {
+		return errorf(http.StatusNotFound, "filter %q not found", name)
+	}
+	switch {
+	case op == "stats" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, f.Stats())
+		return nil
+	case op == "snapshot" && r.Method == http.MethodGet:
+		b, err := f.Encode()
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(b)
+		return nil
+	case r.Method == http.MethodPost:
+		var fn func(item []byte) bool
+		switch op {
+		case "add":
+			fn = f.Add
+		case "addunique":
+			fn = f.AddUnique
+		case "contain":
+			fn = f.Contain
+		case "delete":
+			fn = f.Delete
+		default:
+			return errorf(http.StatusNotFound, "unknown operation %q", op)
+		}
+		return keysOp(w, r, fn)
+	default:
+		return errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request, name string) error {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "invalid body: %v", err)
+	}
+	var tableType uint
+	switch req.TableType {
+	case "", "single":
+		tableType = cuckoo.TableTypeSingle
+	case "packed":
+		tableType = cuckoo.TableTypePacked
+	case "vacuum":
+		tableType = cuckoo.TableTypeVacuum
+	default:
+		return errorf(http.StatusBadRequest, "unknown table type %q", req.TableType)
+	}
+	cfg := cuckoo.Config{TagsPerBucket: req.TagsPerBucket, BitsPerItem: req.BitsPerItem, TableType: tableType}
+	if err := cfg.Validate(); err != nil {
+		return errorf(http.StatusBadRequest, "%v", err)
+	}
+	if req.MaxNumKeys == 0 || req.MaxNumKeys > s.MaxNumKeys {
+		return errorf(http.StatusBadRequest, "maxNumKeys should be within [1, %d] but got %d", s.MaxNumKeys, req.MaxNumKeys)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.filters[name]; ok {
+		return errorf(http.StatusConflict, "filter %q already exists", name)
+	}
+	s.filters[name] = guard(cuckoo.NewFilter(req.TagsPerBucket, req.BitsPerItem, req.MaxNumKeys, tableType))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (s *Server) drop(w http.ResponseWriter, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.filters[name]; !ok {
+		return errorf(http.StatusNotFound, "filter %q not found", name)
+	}
+	delete(s.filters, name)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, name string) error {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "read body: %v", err)
+	}
+	f, err := cuckoo.DecodeFrom(b)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "decode snapshot: %v", err)
+	}
+	s.Set(name, f)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func keysOp(w http.ResponseWriter, r *http.Request, fn func(item []byte) bool) error {
+	var req KeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errorf(http.StatusBadRequest, "invalid body: %v", err)
+	}
+	var resp KeysResponse
+	switch {
+	case req.Key != nil && req.Keys == nil:
+		result := fn([]byte(*req.Key))
+		resp.Result = &result
+	case req.Key == nil && req.Keys != nil:
+		resp.Results = make([]bool, len(req.Keys))
+		for i, key := range req.Keys {
+			resp.Results[i] = fn([]byte(key))
+		}
+	default:
+		return errorf(http.StatusBadRequest, "exactly one of key and keys should be set")
+	}
+	writeJSON(w, http.StatusOK, resp)
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 18:00
+ */
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	cuckoo "github.com/linvon/cuckoo-filter"
+)
+
+func do(t *testing.T, ts *httptest.Server, method, path string, body []byte, code int) []byte {
+	req, _ := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != code {
+		t.Fatalf("%s %s: expected status %d, instead %d %s", method, path, code, resp.StatusCode, b)
+	}
+	return b
+}
+
+func keysResult(t *testing.T, b []byte) KeysResponse {
+	var resp KeysResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		t.Fatalf("err %v", err)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	ts := httptest.NewServer(New())
+	defer ts.Close()
+
+	do(t, ts, "POST", "/filters/users", []byte(`{"tagsPerBucket":4,"bitsPerItem":9,"maxNumKeys":1000,"tableType":"packed"}`), http.StatusCreated)
+	do(t, ts, "POST", "/filters/users", []byte(`{"tagsPerBucket":4,"bitsPerItem":9,"maxNumKeys":1000}`), http.StatusConflict)
+	do(t, ts, "POST", "/filters/bad", []byte(`{"tagsPerBucket":2,"bitsPerItem":9,"maxNumKeys":1000,"tableType":"packed"}`), http.StatusBadRequest)
+	// would allocate far more memory than the server has
+	do(t, ts, "POST", "/filters/huge", []byte(`{"tagsPerBucket":4,"bitsPerItem":32,"maxNumKeys":1000000000000}`), http.StatusBadRequest)
+
+	if resp := keysResult(t, do(t, ts, "POST", "/filters/users/add", []byte(`{"keys":["a","b","c"]}`), http.StatusOK)); !reflect.DeepEqual(resp.Results, []bool{true, true, true}) {
+		t.Fatalf("Unexpected add results %v", resp.Results)
+	}
+	if resp := keysResult(t, do(t, ts, "POST", "/filters/users/addunique", []byte(`{"key":"a"}`), http.StatusOK)); resp.Result == nil || *resp.Result {
+		t.Fatalf("Expected addunique false")
+	}
+	if resp := keysResult(t, do(t, ts, "POST", "/filters/users/delete", []byte(`{"key":"b"}`), http.StatusOK)); resp.Result == nil || !*resp.Result {
+		t.Fatalf("Expected delete true")
+	}
+	if resp := keysResult(t, do(t, ts, "POST", "/filters/users/contain", []byte(`{"keys":["a","b","c"]}`), http.StatusOK)); !reflect.DeepEqual(resp.Results, []bool{true, false, true}) {
+		t.Fatalf("Unexpected contain results %v", resp.Results)
+	}
+	do(t, ts, "POST", "/filters/users/contain", []byte(`{}`), http.StatusBadRequest)
+	do(t, ts, "POST", "/filters/missing/contain", []byte(`{"key":"a"}`), http.StatusNotFound)
+
+	var stats cuckoo.Stats
+	_ = json.Unmarshal(do(t, ts, "GET", "/filters/users/stats", nil, http.StatusOK), &stats)
+	if stats.Items != 2 || stats.TableType != cuckoo.TableTypePacked || stats.Deletes != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	snapshot := do(t, ts, "GET", "/filters/users/snapshot", nil, http.StatusOK)
+	do(t, ts, "PUT", "/filters/copy/snapshot", snapshot, http.StatusNoContent)
+	do(t, ts, "PUT", "/filters/copy2/snapshot", []byte("garbage"), http.StatusBadRequest)
+	// a single table of zero bits per item, which would make every later request panic
+	crafted := append(make([]byte, 13), cuckoo.TableTypeSingle, 4, 0, 1, 0, 0, 0)
+	do(t, ts, "PUT", "/filters/copy2/snapshot", crafted, http.StatusBadRequest)
+	if resp := keysResult(t, do(t, ts, "POST", "/filters/copy/contain", []byte(`{"keys":["a","b","c"]}`), http.StatusOK)); !reflect.DeepEqual(resp.Results, []bool{true, false, true}) {
+		t.Fatalf("Unexpected contain results of uploaded snapshot %v", resp.Results)
+	}
+
+	var names []string
+	_ = json.Unmarshal(do(t, ts, "GET", "/filters", nil, http.StatusOK), &names)
+	if !reflect.DeepEqual(names, []string{"copy", "users"}) {
+		t.Fatalf("Unexpected names %v", names)
+	}
+	do(t, ts, "DELETE", "/filters/copy", nil, http.StatusNoContent)
+	do(t, ts, "DELETE", "/filters/copy", nil, http.StatusNotFound)
+	do(t, ts, "GET", "/other", nil, http.StatusNotFound)
+}
+
+func TestServerConcurrent(t *testing.T) {
+	s := New()
+	s.Set("f", cuckoo.NewFilter(4, 9, 10000, cuckoo.TableTypeSingle))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				body, _ := json.Marshal(KeysRequest{Keys: []string{fmt.Sprintf("%d-%d", g, i)}})
+				req, _ := http.NewRequest("POST", ts.URL+"/filters/f/add", bytes.NewReader(body))
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Errorf("err %v", err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}(g)
+	}
+	wg.Wait()
+	if f, _ := s.Filter("f"); f.Size() != 200 {
+		t.Fatalf("Expected size 200, instead %d", f.Size())
+	}
+}
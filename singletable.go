@@ -21,6 +21,7 @@ type SingleTable struct {
 	tagMask        uint32
 	bucket         []byte
 	len            uint
+	dirty          dirtyPages
 }
 
 // NewSingleTable return a singleTable
@@ -41,14 +42,28 @@ func (t *SingleTable) Init(tagsPerBucket, bitsPerTag, num uint, initialBucketsHi
 		return err
 	}
 	t.bucket = buckets
+	if t.dirty.enabled() {
+		t.dirty.init(t.numBuckets, 1<<t.dirty.pageShift)
+		t.dirty.markAll()
+	}
 	return nil
 }
 
+// TableType return TableTypeSingle
+func (t *SingleTable) TableType() uint {
+	return TableTypeSingle
+}
+
 // NumBuckets return num of table buckets
 func (t *SingleTable) NumBuckets() uint {
 	return t.numBuckets
 }
 
+// TagsPerBucket return num of tags that each bucket can store
+func (t *SingleTable) TagsPerBucket() uint {
+	return t.kTagsPerBucket
+}
+
 // SizeInBytes return bytes occupancy of table
 func (t *SingleTable) SizeInBytes() uint {
 	return t.len
@@ -89,6 +104,13 @@ func (t *SingleTable) ReadTag(i, j uint) uint32 {
 	return tag & t.tagMask
 }
 
+// ReadTagsFromBucket read all tags of bucket i into tags, empty slots are read as 0
+func (t *SingleTable) ReadTagsFromBucket(i uint, tags []uint32) {
+	for j := uint(0); j < t.kTagsPerBucket; j++ {
+		tags[j] = t.ReadTag(i, j)
+	}
+}
+
 func (t *SingleTable) readOutBytes(i, j, pos uint) uint32 {
 	rShift := (i*t.bitsPerTag*t.kTagsPerBucket + t.bitsPerTag*j) & (bitsPerByte - 1)
 	// tag is max 32bit, so max occupies 5 bytes
@@ -103,6 +125,7 @@ func (t *SingleTable) readOutBytes(i, j, pos uint) uint32 {
 
 // WriteTag write tag into bucket(i,j)
 func (t *SingleTable) WriteTag(i, j uint, n uint32) {
+	t.dirty.mark(i)
 	pos := (i*t.bitsPerTag*t.kTagsPerBucket + t.bitsPerTag*j) / bitsPerByte
 	tag := n & t.tagMask
 	/* following code only works for little-endian */
@@ -168,6 +191,21 @@ func (t *SingleTable) writeInBytes(i, j, pos uint, tag uint32) {
 	}
 }
 
+func (t *SingleTable) touchBucket(i uint) byte {
+	return t.bucket[i*t.bitsPerTag*t.kTagsPerBucket/bitsPerByte]
+}
+
+// FindTagInBucket find if tag in bucket i
+func (t *SingleTable) FindTagInBucket(i uint, tag uint32) bool {
+	var j uint
+	for j = 0; j < t.kTagsPerBucket; j++ {
+		if t.ReadTag(i, j) == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // FindTagInBuckets find if tag in bucket i1 i2
 func (t *SingleTable) FindTagInBuckets(i1, i2 uint, tag uint32) bool {
 	var j uint
@@ -213,6 +251,50 @@ func (t *SingleTable) Reset() {
 	for i := range t.bucket {
 		t.bucket[i] = 0
 	}
+	if t.dirty.enabled() {
+		t.dirty.markAll()
+	}
+}
+
+func (t *SingleTable) clone() table {
+	nt := *t
+	nt.bucket = append([]byte(nil), t.bucket...)
+	nt.dirty.bits = append([]uint64(nil), t.dirty.bits...)
+	return &nt
+}
+
+// EnableDirtyTracking start recording written buckets in pages of bucketsPerPage buckets,
+// bucketsPerPage is rounded up to a power of two
+func (t *SingleTable) EnableDirtyTracking(bucketsPerPage uint) {
+	t.dirty.init(t.numBuckets, bucketsPerPage)
+}
+
+// DisableDirtyTracking stop recording written buckets
+func (t *SingleTable) DisableDirtyTracking() {
+	t.dirty.disable()
+}
+
+// RangeDirty call fn with offset and length of each dirty byte range in the encoded table,
+// the whole table is reported when tracking is disabled, iteration stops when fn return false
+func (t *SingleTable) RangeDirty(fn func(offset, length uint) bool) {
+	if !t.dirty.enabled() {
+		fn(singleTableMetadataSize, t.len)
+		return
+	}
+	bitsPerBucket := t.bitsPerTag * t.kTagsPerBucket
+	t.dirty.rangeDirty(func(first, end uint) bool {
+		start := first * bitsPerBucket / bitsPerByte
+		stop := (end*bitsPerBucket + 7) / bitsPerByte
+		if stop > t.len {
+			stop = t.len
+		}
+		return fn(singleTableMetadataSize+start, stop-start)
+	})
+}
+
+// ClearDirty mark all buckets as clean
+func (t *SingleTable) ClearDirty() {
+	t.dirty.clear()
 }
 
 // Info return table's info
@@ -228,8 +310,12 @@ const singleTableMetadataSize = 3 + bytesPerUint32
 
 // Encode returns a byte slice representing a TableBucket
 func (t *SingleTable) Reader() (io.Reader, uint) {
+	return t.reader(TableTypeSingle)
+}
+
+func (t *SingleTable) reader(tableType uint) (io.Reader, uint) {
 	var metadata [singleTableMetadataSize]byte
-	metadata[0] = uint8(TableTypeSingle)
+	metadata[0] = uint8(tableType)
 	metadata[1] = uint8(t.kTagsPerBucket)
 	metadata[2] = uint8(t.bitsPerTag)
 	binary.LittleEndian.PutUint32(metadata[3:], uint32(t.numBuckets))
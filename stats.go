@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 15:10
+ */
+
+package cuckoo
+
+import (
+	"sync/atomic"
+)
+
+// Stats is a snapshot of filter's status
+type Stats struct {
+	// TableType is TableTypeSingle, TableTypePacked or TableTypeVacuum
+	TableType uint
+	// NumBuckets is num of buckets of table
+	NumBuckets uint
+	// TagsPerBucket is num of slots of each bucket
+	TagsPerBucket uint
+	// Slots is num of slots of table, that is NumBuckets * TagsPerBucket
+	Slots uint
+	// Items is num of items that filter store, including the victim
+	Items uint
+	// VictimUsed is true when an item is kept out of table, Add return false until it is placed
+	VictimUsed bool
+	// LoadFactor is Items / Slots
+	LoadFactor float64
+	// FingerprintBits is length of tag(fingerprint)
+	FingerprintBits uint
+	// BitsPerItem is bits occupancy of table per stored item
+	BitsPerItem float64
+	// SizeInBytes is bytes occupancy of table
+	SizeInBytes uint
+	// Occupancy[k] is num of buckets holding k tags, k is from 0 to TagsPerBucket. It is nil in Summary
+	Occupancy []uint
+
+	// Inserts is num of items inserted
+	Inserts uint64
+	// FailedInserts is num of insertions rejected since filter is full
+	FailedInserts uint64
+	// Kicks is num of tags moved to their alternate bucket to make room for insertions
+	Kicks uint64
+	// Deletes is num of items deleted
+	Deletes uint64
+	// Lookups is num of membership checks, including the ones made by AddUnique
+	Lookups uint64
+	// LookupHits is num of membership checks returning true
+	LookupHits uint64
+	// KickHistogram[0] is num of insertions without kicks, and KickHistogram[k] is num of insertions
+	// with kicks in [2^(k-1), 2^k). Insertions which end in the victim are counted with kMaxCuckooCount kicks.
+	// Sum of KickHistogram can exceed Inserts since deleting an item can reinsert the victim
+	KickHistogram []uint64
+}
+
+// Stats return a snapshot of filter's status, the counters are cumulative since EnableCounters is called
+// and are zero before, they are not reset by Reset. Occupancy is computed by reading every bucket,
+// use Summary to poll frequently
+func (f *Filter) Stats() Stats {
+	s := f.Summary()
+	tagsPerBucket := f.table.TagsPerBucket()
+	s.Occupancy = make([]uint, tagsPerBucket+1)
+	tags := make([]uint32, tagsPerBucket)
+	for i := uint(0); i < s.NumBuckets; i++ {
+		f.table.ReadTagsFromBucket(i, tags)
+		var n uint
+		for _, tag := range tags {
+			if tag != 0 {
+				n++
+			}
+		}
+		s.Occupancy[n]++
+	}
+	return s
+}
+
+// Summary return Stats without Occupancy, it does not read buckets, so it is cheap enough to poll frequently
+func (f *Filter) Summary() Stats {
+	s := Stats{
+		TableType:       f.table.TableType(),
+		NumBuckets:      f.table.NumBuckets(),
+		TagsPerBucket:   f.table.TagsPerBucket(),
+		Slots:           f.table.SizeInTags(),
+		Items:           f.Size(),
+		VictimUsed:      f.victim.used,
+		LoadFactor:      f.LoadFactor(),
+		FingerprintBits: f.table.BitsPerItem(),
+		BitsPerItem:     f.BitsPerItem(),
+		SizeInBytes:     f.table.SizeInBytes(),
+		KickHistogram:   f.KickHistogram(),
+	}
+	if c := f.counters; c != nil {
+		s.Inserts = c.inserts
+		s.FailedInserts = c.failedInserts
+		s.Kicks = c.kicks
+		s.Deletes = c.deletes
+		s.Lookups = atomic.LoadUint64(&c.lookups)
+		s.LookupHits = atomic.LoadUint64(&c.lookupHits)
+	}
+	return s
+}
+
+// KickHistogram return num of insertions by num of kicks, see Stats.KickHistogram.
+// Like Summary, it does not read buckets, so it is cheap enough to poll frequently
+func (f *Filter) KickHistogram() []uint64 {
+	if f.counters == nil {
+		return make([]uint64, kickHistogramSize)
+	}
+	return append([]uint64(nil), f.counters.kickHistogram[:]...)
+}
+
+// OnInsert set fn to be called after every insertion with num of kicks it took and whether it succeed,
+// nil remove the hook. Kicks grow as filter is getting full, and an insertion taking kMaxCuckooCount(500) kicks
+// leaves an item in the victim, after which insertions fail with ok false and kicks 0 until an item is deleted.
+// Reinsertion of the victim by Delete is reported too. fn is called synchronously, it should be fast
+func (f *Filter) OnInsert(fn func(kicks int, ok bool)) {
+	f.onInsert = fn
+}
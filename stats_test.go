@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 15:10
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"reflect"
+	"testing"
+)
+
+func TestFilterStats(t *testing.T) {
+	for _, table := range testTableType {
+		cf := NewFilter(4, 9, 1000, table)
+		cf.EnableCounters()
+		item := make([]byte, 8)
+		var added uint64
+		for i := uint64(0); ; i++ {
+			binary.LittleEndian.PutUint64(item, i)
+			if !cf.Add(item) {
+				break
+			}
+			added++
+		}
+		// the victim is reinserted when an item in table is deleted
+		var reinserted uint64
+		for i := uint64(0); i < 10; i++ {
+			binary.LittleEndian.PutUint64(item, i)
+			i1, tag := cf.generateIndexTagHash(item)
+			if cf.victim.used && cf.table.FindTagInBuckets(i1, cf.altIndex(i1, tag), tag) {
+				reinserted++
+			}
+			cf.Delete(item)
+		}
+
+		s := cf.Stats()
+		if s.TableType != table || s.TagsPerBucket != 4 || s.FingerprintBits != 9 || s.Slots != s.NumBuckets*4 {
+			t.Fatalf("Unexpected table stats %+v", s)
+		}
+		if s.Items != cf.Size() || s.LoadFactor != cf.LoadFactor() || s.VictimUsed != cf.victim.used {
+			t.Fatalf("Unexpected item stats %+v", s)
+		}
+		if s.Inserts != added || s.FailedInserts != 1 || s.Deletes != 10 || s.Kicks == 0 {
+			t.Fatalf("Unexpected counters %+v, added %d", s, added)
+		}
+		var insertions uint64
+		for _, n := range s.KickHistogram {
+			insertions += n
+		}
+		if insertions != added+reinserted || s.KickHistogram[len(s.KickHistogram)-1] == 0 {
+			t.Fatalf("Unexpected kick histogram %v, added %d", s.KickHistogram, added)
+		}
+		cf.Contain(item)
+		if s = cf.Stats(); s.Lookups != 1 || s.LookupHits != 0 {
+			t.Fatalf("Unexpected lookups %d, hits %d", s.Lookups, s.LookupHits)
+		}
+
+		var buckets, tags uint
+		for k, n := range s.Occupancy {
+			buckets += n
+			tags += uint(k) * n
+		}
+		if buckets != s.NumBuckets || tags != s.Items {
+			t.Fatalf("Unexpected occupancy %v, buckets %d, items %d", s.Occupancy, s.NumBuckets, s.Items)
+		}
+		// Summary is Stats without reading buckets
+		s.Occupancy = nil
+		if summary := cf.Summary(); !reflect.DeepEqual(summary, s) {
+			t.Fatalf("Expected summary %+v, instead %+v", s, summary)
+		}
+	}
+}
+
+func TestFilterOnInsert(t *testing.T) {
+	cf := NewFilter(4, 9, 1000, TableTypeSingle)
+	cf.EnableCounters()
+	var histogram [kickHistogramSize]uint64
+	var inserted, rejected, maxKicks int
+	cf.OnInsert(func(kicks int, ok bool) {
+		if !ok {
+			rejected++
+			return
+		}
+		inserted++
+		if kicks > maxKicks {
+			maxKicks = kicks
+		}
+		histogram[bits.Len(uint(kicks))]++
+	})
+	item := make([]byte, 8)
+	for i := uint64(0); rejected == 0; i++ {
+		binary.LittleEndian.PutUint64(item, i)
+		cf.Add(item)
+	}
+	if uint(inserted) != cf.Size() || maxKicks != int(kMaxCuckooCount) {
+		t.Fatalf("Unexpected inserted %d, size %d, max kicks %d", inserted, cf.Size(), maxKicks)
+	}
+	if got := cf.KickHistogram(); !reflect.DeepEqual(got, histogram[:]) {
+		t.Fatalf("Expected histogram %v, instead %v", histogram, got)
+	}
+
+	cf.OnInsert(nil)
+	cf.Add(item)
+	if rejected != 1 || cf.Stats().FailedInserts != 2 {
+		t.Fatalf("Unexpected rejected %d after removing hook", rejected)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 18:00
+ */
+
+package cuckoo
+
+import (
+	"sync"
+)
+
+// SyncFilter is a Filter safe for concurrent use by multiple goroutines.
+// Modifications are serialized by a RWMutex, while lookups run concurrently
+type SyncFilter struct {
+	mu     sync.RWMutex
+	filter *Filter
+}
+
+// NewSyncFilter return a SyncFilter guarding f, f should not be used directly afterwards
+func NewSyncFilter(f *Filter) *SyncFilter {
+	return &SyncFilter{filter: f}
+}
+
+// Do call fn with the filter while holding the lock, fn must not keep f after return
+func (s *SyncFilter) Do(fn func(f *Filter)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.filter)
+}
+
+// Add add an item into filter, return false when filter is full
+func (s *SyncFilter) Add(item []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.Add(item)
+}
+
+// AddUnique add an item into filter, return false when filter already contains it or filter is full
+func (s *SyncFilter) AddUnique(item []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.AddUnique(item)
+}
+
+// Contain return if filter contains an item
+func (s *SyncFilter) Contain(item []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Contain(item)
+}
+
+// Delete delete item from filter, return false when item not exist
+func (s *SyncFilter) Delete(item []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.Delete(item)
+}
+
+// AddBatch add items into filter, see Filter.AddBatch
+func (s *SyncFilter) AddBatch(items [][]byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.AddBatch(items)
+}
+
+// ContainBatch set out[i] to whether filter contains items[i], see Filter.ContainBatch
+func (s *SyncFilter) ContainBatch(items [][]byte, out []bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.filter.ContainBatch(items, out)
+}
+
+// Size return num of items that filter store
+func (s *SyncFilter) Size() uint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Size()
+}
+
+// LoadFactor return current filter's loadFactor
+func (s *SyncFilter) LoadFactor() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.LoadFactor()
+}
+
+// Reset reset the filter
+func (s *SyncFilter) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter.Reset()
+}
+
+// EnableCounters start counting operations, see Filter.EnableCounters
+func (s *SyncFilter) EnableCounters() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter.EnableCounters()
+}
+
+// Stats return a snapshot of filter's status
+func (s *SyncFilter) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Stats()
+}
+
+// Summary return a snapshot of filter's status without Occupancy, see Filter.Summary
+func (s *SyncFilter) Summary() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Summary()
+}
+
+// Info return filter's detail info
+func (s *SyncFilter) Info() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Info()
+}
+
+// Encode returns a byte slice representing the filter
+func (s *SyncFilter) Encode() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter.Encode()
+}
+
+// Clone return a copy of the filter, which is not guarded. It locks exclusively to copy lookup counters
+func (s *SyncFilter) Clone() *Filter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter.Clone()
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 18:00
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+)
+
+func TestSyncFilter(t *testing.T) {
+	sf := NewSyncFilter(NewFilter(4, 9, 10000, TableTypeSingle))
+	sf.EnableCounters()
+	var wg sync.WaitGroup
+	for g := uint64(0); g < 4; g++ {
+		wg.Add(1)
+		go func(g uint64) {
+			defer wg.Done()
+			item := make([]byte, 8)
+			for i := uint64(0); i < 1000; i++ {
+				binary.LittleEndian.PutUint64(item, g<<32|i)
+				sf.Add(item)
+				sf.Contain(item)
+			}
+			sf.Stats()
+		}(g)
+	}
+	wg.Wait()
+	if sf.Size() != 4000 {
+		t.Fatalf("Expected size 4000, instead %d", sf.Size())
+	}
+	sf.Do(func(f *Filter) {
+		if s := f.Stats(); s.Lookups != 4000 || s.LookupHits != 4000 {
+			t.Fatalf("Unexpected lookups %d, hits %d", s.Lookups, s.LookupHits)
+		}
+	})
+}
+
+func TestSyncFilterConcurrentLookups(t *testing.T) {
+	for _, table := range testTableType {
+		sf := NewSyncFilter(NewFilter(4, 9, 10000, table))
+		sf.EnableCounters()
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = make([]byte, 8)
+			binary.LittleEndian.PutUint64(items[i], uint64(i))
+			sf.Add(items[i])
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				out := make([]bool, len(items))
+				for _, item := range items {
+					if !sf.Contain(item) {
+						t.Errorf("Expected contain %v", item)
+					}
+				}
+				sf.ContainBatch(items, out)
+				sf.Stats()
+			}()
+		}
+		wg.Wait()
+		if s := sf.Stats(); s.Lookups != 8000 || s.LookupHits != 8000 {
+			t.Fatalf("Unexpected lookups %d, hits %d", s.Lookups, s.LookupHits)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Generates testdata/cpp_singletable.golden.
+//
+// This program reproduces the pieces of efficient/cuckoofilter that determine
+// its memory layout: TwoIndependentMultiplyShift from hashutil.h, the index and
+// tag derivation and AddImpl of cuckoofilter.h, and SingleTable<bits_per_tag>
+// from singletable.h. The hash factors are fixed instead of drawn from
+// std::random_device so the output is reproducible. It does not link the library,
+// so the vectors are only as faithful as this reimplementation.
+//
+//   g++ -O2 -std=c++11 -o cpp_golden cpp_golden.cc && ./cpp_golden > cpp_singletable.golden
+
+#include <cstdint>
+#include <cstdio>
+#include <cstdlib>
+#include <cstring>
+
+static const unsigned __int128 kMultiply =
+    ((unsigned __int128)0x9e3779b97f4a7c15ULL << 64) | 0xbf58476d1ce4e5b9ULL;
+static const unsigned __int128 kAdd =
+    ((unsigned __int128)0x94d049bb133111ebULL << 64) | 0x2545f4914f6cdd1dULL;
+
+static uint64_t Hash(uint64_t key) {
+  return (kAdd + kMultiply * static_cast<unsigned __int128>(key)) >> 64;
+}
+
+template <size_t bits_per_tag>
+class SingleTable {
+ public:
+  static const size_t kTagsPerBucket = 4;
+  static const size_t kBytesPerBucket = (bits_per_tag * kTagsPerBucket + 7) >> 3;
+  static const uint32_t kTagMask = (1ULL << bits_per_tag) - 1;
+  static const size_t kPaddingBuckets =
+      ((((kBytesPerBucket + 7) / 8) * 8) - 1) / kBytesPerBucket;
+
+  struct Bucket {
+    char bits_[kBytesPerBucket];
+  } __attribute__((__packed__));
+
+  Bucket *buckets_;
+  size_t num_buckets_;
+
+  explicit SingleTable(const size_t num) : num_buckets_(num) {
+    buckets_ = new Bucket[num_buckets_ + kPaddingBuckets];
+    memset(buckets_, 0, kBytesPerBucket * (num_buckets_ + kPaddingBuckets));
+  }
+
+  size_t SizeInBytes() const { return kBytesPerBucket * num_buckets_; }
+
+  uint32_t ReadTag(const size_t i, const size_t j) const {
+    const char *p = buckets_[i].bits_;
+    uint32_t tag;
+    if (bits_per_tag == 2) {
+      tag = *((uint8_t *)p) >> (j * 2);
+    } else if (bits_per_tag == 4) {
+      p += (j >> 1);
+      tag = *((uint8_t *)p) >> ((j & 1) << 2);
+    } else if (bits_per_tag == 8) {
+      p += j;
+      tag = *((uint8_t *)p);
+    } else if (bits_per_tag == 12) {
+      p += j + (j >> 1);
+      tag = *((uint16_t *)p) >> ((j & 1) << 2);
+    } else if (bits_per_tag == 16) {
+      p += (j << 1);
+      tag = *((uint16_t *)p);
+    } else if (bits_per_tag == 32) {
+      tag = ((uint32_t *)p)[j];
+    }
+    return tag & kTagMask;
+  }
+
+  void WriteTag(const size_t i, const size_t j, const uint32_t t) {
+    char *p = buckets_[i].bits_;
+    uint32_t tag = t & kTagMask;
+    if (bits_per_tag == 2) {
+      *((uint8_t *)p) |= tag << (2 * j);
+    } else if (bits_per_tag == 4) {
+      p += (j >> 1);
+      if ((j & 1) == 0) {
+        *((uint8_t *)p) &= 0xf0;
+        *((uint8_t *)p) |= tag;
+      } else {
+        *((uint8_t *)p) &= 0x0f;
+        *((uint8_t *)p) |= (tag << 4);
+      }
+    } else if (bits_per_tag == 8) {
+      ((uint8_t *)p)[j] = tag;
+    } else if (bits_per_tag == 12) {
+      p += (j + (j >> 1));
+      if ((j & 1) == 0) {
+        ((uint16_t *)p)[0] &= 0xf000;
+        ((uint16_t *)p)[0] |= tag;
+      } else {
+        ((uint16_t *)p)[0] &= 0x000f;
+        ((uint16_t *)p)[0] |= (tag << 4);
+      }
+    } else if (bits_per_tag == 16) {
+      ((uint16_t *)p)[j] = tag;
+    } else if (bits_per_tag == 32) {
+      ((uint32_t *)p)[j] = tag;
+    }
+  }
+
+  bool InsertTagToBucket(const size_t i, const uint32_t tag, const bool kickout,
+                         uint32_t &oldtag) {
+    for (size_t j = 0; j < kTagsPerBucket; j++) {
+      if (ReadTag(i, j) == 0) {
+        WriteTag(i, j, tag);
+        return true;
+      }
+    }
+    if (kickout) {
+      size_t r = rand() % kTagsPerBucket;
+      oldtag = ReadTag(i, r);
+      WriteTag(i, r, tag);
+    }
+    return false;
+  }
+};
+
+template <size_t bits_per_item>
+class Filter {
+ public:
+  SingleTable<bits_per_item> *table_;
+  size_t num_items_;
+  struct {
+    size_t index;
+    uint32_t tag;
+    bool used;
+  } victim_;
+
+  explicit Filter(const size_t num_buckets) : num_items_(0) {
+    victim_.index = 0;
+    victim_.tag = 0;
+    victim_.used = false;
+    table_ = new SingleTable<bits_per_item>(num_buckets);
+  }
+
+  size_t IndexHash(uint32_t hv) const { return hv & (table_->num_buckets_ - 1); }
+
+  uint32_t TagHash(uint32_t hv) const {
+    uint32_t tag;
+    tag = hv & ((1ULL << bits_per_item) - 1);
+    tag += (tag == 0);
+    return tag;
+  }
+
+  void GenerateIndexTagHash(uint64_t item, size_t *index, uint32_t *tag) const {
+    const uint64_t hash = Hash(item);
+    *index = IndexHash(hash >> 32);
+    *tag = TagHash(hash);
+  }
+
+  size_t AltIndex(const size_t index, const uint32_t tag) const {
+    return IndexHash((uint32_t)(index ^ (tag * 0x5bd1e995)));
+  }
+
+  bool Add(uint64_t item) {
+    size_t i;
+    uint32_t tag;
+    if (victim_.used) return false;
+    GenerateIndexTagHash(item, &i, &tag);
+    size_t curindex = i;
+    uint32_t curtag = tag;
+    uint32_t oldtag;
+    for (uint32_t count = 0; count < 500; count++) {
+      bool kickout = count > 0;
+      oldtag = 0;
+      if (table_->InsertTagToBucket(curindex, curtag, kickout, oldtag)) {
+        num_items_++;
+        return true;
+      }
+      if (kickout) curtag = oldtag;
+      curindex = AltIndex(curindex, curtag);
+    }
+    victim_.index = curindex;
+    victim_.tag = curtag;
+    victim_.used = true;
+    return true;
+  }
+};
+
+static uint64_t Key(size_t i) { return i * 0x9e3779b97f4a7c15ULL + 1; }
+
+template <size_t bits_per_item>
+static void Dump(size_t num_buckets, size_t num_keys) {
+  Filter<bits_per_item> f(num_buckets);
+  size_t added = 0;
+  while (added < num_keys && f.Add(Key(added))) added++;
+
+  printf("filter %zu %zu %zu %zu %d %zu %u\n", bits_per_item, num_buckets, added,
+         f.num_items_, f.victim_.used ? 1 : 0, f.victim_.index, f.victim_.tag);
+  for (size_t k = 0; k < 8; k++) {
+    size_t i;
+    uint32_t tag;
+    f.GenerateIndexTagHash(Key(k), &i, &tag);
+    printf("hash %llu %llu %zu %u\n", (unsigned long long)Key(k),
+           (unsigned long long)Hash(Key(k)), i, tag);
+  }
+  printf("table ");
+  const unsigned char *p = (const unsigned char *)f.table_->buckets_;
+  for (size_t k = 0; k < f.table_->SizeInBytes(); k++) printf("%02x", p[k]);
+  printf("\n");
+}
+
+int main() {
+  srand(1);
+  printf("multiply %016llx%016llx\n", (unsigned long long)(kMultiply >> 64),
+         (unsigned long long)kMultiply);
+  printf("add %016llx%016llx\n", (unsigned long long)(kAdd >> 64),
+         (unsigned long long)kAdd);
+  Dump<4>(64, 230);
+  Dump<8>(64, 240);
+  Dump<12>(128, 480);
+  Dump<16>(256, 960);
+  Dump<32>(32, 120);
+  return 0;
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 14:30
+ */
+
+package cuckoo
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Encoder append the byte encoding of key to dst and return the extended slice,
+// equal keys must have equal encodings
+type Encoder[K any] func(dst []byte, key K) []byte
+
+// Integer is the constraint of integer key types
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// EncodeString encode a string key as its bytes, so a key has the same fingerprint as with AddString
+func EncodeString[K ~string](dst []byte, key K) []byte {
+	return append(dst, key...)
+}
+
+// EncodeInteger encode an integer key in 8 bytes little-endian, so a key has the same fingerprint as with AddUint64.
+// Signed keys are sign extended
+func EncodeInteger[K Integer](dst []byte, key K) []byte {
+	return appendUint64(dst, uint64(key), bytesPerUint64)
+}
+
+func appendUint64(dst []byte, n uint64, size int) []byte {
+	for i := 0; i < size; i++ {
+		dst = append(dst, byte(n>>(i*bitsPerByte)))
+	}
+	return dst
+}
+
+// ArrayEncoder return an Encoder of fixed-size arrays of integers, such as [16]byte,
+// which encode elements in order, each in little-endian of its own size. It panics if K is not such an array
+func ArrayEncoder[K comparable]() Encoder[K] {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	if t.Kind() != reflect.Array {
+		panic(fmt.Sprintf("cuckoo: %v is not an array", t))
+	}
+	size := int(t.Elem().Size())
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(dst []byte, key K) []byte {
+			v := reflect.ValueOf(key)
+			for i := 0; i < v.Len(); i++ {
+				dst = appendUint64(dst, uint64(v.Index(i).Int()), size)
+			}
+			return dst
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(dst []byte, key K) []byte {
+			v := reflect.ValueOf(key)
+			for i := 0; i < v.Len(); i++ {
+				dst = appendUint64(dst, v.Index(i).Uint(), size)
+			}
+			return dst
+		}
+	default:
+		panic(fmt.Sprintf("cuckoo: %v is not an array of integers", t))
+	}
+}
+
+// TypedFilter is a Filter over keys of type K, which are encoded into bytes by an Encoder.
+// Like Filter, it is not safe for concurrent use
+type TypedFilter[K comparable] struct {
+	filter *Filter
+	encode Encoder[K]
+	// buf is reused to encode keys
+	buf []byte
+}
+
+// NewTypedFilter return a new initialized typed filter, see NewFilter for the parameters
+func NewTypedFilter[K comparable](encode Encoder[K], tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) *TypedFilter[K] {
+	return WrapFilter(NewFilter(tagsPerBucket, bitsPerItem, maxNumKeys, tableType), encode)
+}
+
+// WrapFilter return a typed filter backed by f, such as filters from BuildFilter or ImportCpp
+func WrapFilter[K comparable](f *Filter, encode Encoder[K]) *TypedFilter[K] {
+	return &TypedFilter[K]{
+		filter: f,
+		encode: encode,
+	}
+}
+
+// BuildTypedFilter return a typed filter containing all keys, see BuildFilter
+func BuildTypedFilter[K comparable](keys []K, encode Encoder[K], cfg Config) (*TypedFilter[K], error) {
+	items := make([][]byte, len(keys))
+	for i, key := range keys {
+		items[i] = encode(nil, key)
+	}
+	f, err := BuildFilter(items, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return WrapFilter(f, encode), nil
+}
+
+// DecodeTypedFilter returns a typed filter using a copy of the provided byte slice, see Decode
+func DecodeTypedFilter[K comparable](b []byte, encode Encoder[K]) (*TypedFilter[K], error) {
+	f, err := Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return WrapFilter(f, encode), nil
+}
+
+func (t *TypedFilter[K]) item(key K) []byte {
+	t.buf = t.encode(t.buf[:0], key)
+	return t.buf
+}
+
+// Filter return the underlying filter
+func (t *TypedFilter[K]) Filter() *Filter {
+	return t.filter
+}
+
+// Add add a key into filter, return false when filter is full
+func (t *TypedFilter[K]) Add(key K) bool {
+	return t.filter.Add(t.item(key))
+}
+
+// AddUnique add a key into filter, return false when filter already contains it or filter is full
+func (t *TypedFilter[K]) AddUnique(key K) bool {
+	return t.filter.AddUnique(t.item(key))
+}
+
+// Contain return if filter contains a key
+func (t *TypedFilter[K]) Contain(key K) bool {
+	return t.filter.Contain(t.item(key))
+}
+
+// Delete delete a key from filter, return false when key not exist
+func (t *TypedFilter[K]) Delete(key K) bool {
+	return t.filter.Delete(t.item(key))
+}
+
+// Size return num of keys that filter store
+func (t *TypedFilter[K]) Size() uint {
+	return t.filter.Size()
+}
+
+// LoadFactor return current filter's loadFactor
+func (t *TypedFilter[K]) LoadFactor() float64 {
+	return t.filter.LoadFactor()
+}
+
+// Reset reset the filter
+func (t *TypedFilter[K]) Reset() {
+	t.filter.Reset()
+}
+
+// Info return filter's detail info
+func (t *TypedFilter[K]) Info() string {
+	return t.filter.Info()
+}
+
+// Encode returns a byte slice representing the filter, which can be decoded by DecodeTypedFilter or Decode
+func (t *TypedFilter[K]) Encode() ([]byte, error) {
+	return t.filter.Encode()
+}
+
+// EncodeReader returns a reader representing the filter
+func (t *TypedFilter[K]) EncodeReader() (io.Reader, uint) {
+	return t.filter.EncodeReader()
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 14:30
+ */
+
+package cuckoo
+
+import (
+	"strconv"
+	"testing"
+)
+
+type userID string
+
+func TestTypedFilter(t *testing.T) {
+	for _, table := range testTableType {
+		tf := NewTypedFilter[userID](EncodeString[userID], 4, 9, 10000, table)
+		for i := 0; i < 1000; i++ {
+			if !tf.Add(userID("user" + strconv.Itoa(i))) {
+				t.Fatalf("Expected add ok, table type %v", table)
+			}
+		}
+		if !tf.Filter().ContainString("user7") {
+			t.Fatalf("Expected typed key to match string key")
+		}
+
+		encodedBytes, err := tf.Encode()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		ntf, err := DecodeTypedFilter[userID](encodedBytes, EncodeString[userID])
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+		for i := 0; i < 1000; i++ {
+			if !ntf.Contain(userID("user" + strconv.Itoa(i))) {
+				t.Fatalf("Expected contain after decode, table type %v", table)
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			if !ntf.Delete(userID("user" + strconv.Itoa(i))) {
+				t.Fatalf("Expected delete, table type %v", table)
+			}
+		}
+		if ntf.Size() != 0 {
+			t.Fatalf("Expected empty filter, instead size %d", ntf.Size())
+		}
+	}
+
+	tf := NewTypedFilter[int32](EncodeInteger[int32], 4, 12, 1000, TableTypeSingle)
+	tf.Add(-5)
+	if !tf.Filter().ContainUint64(uint64(0xfffffffffffffffb)) {
+		t.Errorf("Expected sign extended integer key")
+	}
+
+	keys := make([][4]uint16, 2000)
+	for i := range keys {
+		keys[i] = [4]uint16{uint16(i), uint16(i >> 16), 7, 1}
+	}
+	af, err := BuildTypedFilter(keys, ArrayEncoder[[4]uint16](), Config{TagsPerBucket: 4, BitsPerItem: 12})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	for _, key := range keys {
+		if !af.Contain(key) {
+			t.Fatalf("Expected contain array key %v", key)
+		}
+	}
+	if got := string(ArrayEncoder[[2]uint16]()(nil, [2]uint16{0x0102, 0x0304})); got != "\x02\x01\x04\x03" {
+		t.Errorf("Unexpected array encoding %q", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for array of strings")
+		}
+	}()
+	ArrayEncoder[[2]string]()
+}
@@ -46,3 +46,12 @@ func getBucketsFromHint(initialBucketsHint []byte, expectedLength uint) ([]byte,
 	}
 	return result, nil
 }
+
+// sortTags sort the few tags of a bucket with insertion sort
+func sortTags(tags []uint32) {
+	for i := 1; i < len(tags); i++ {
+		for j := i; j > 0 && tags[j] < tags[j-1]; j-- {
+			tags[j], tags[j-1] = tags[j-1], tags[j]
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/20 16:00
+ */
+
+package cuckoo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// vacuumMinChunkSlots is the min num of slots in a chunk of VacuumTable, except tables of a single chunk,
+	// num of items hashed to smaller chunks vary too much, which lowers load factor a lot
+	vacuumMinChunkSlots = 1024
+	// vacuumMaxChunk is the max num of buckets in a chunk of VacuumTable
+	vacuumMaxChunk = 1 << 16
+	// vacuumLoadMargin is the ratio of max load factor planned by NewFilter, since alternate buckets
+	// limited in chunks reach a bit lower load than power of two tables
+	vacuumLoadMargin = 0.95
+)
+
+// VacuumTable is a SingleTable of any num of buckets, indexed like vacuum filters.
+// Buckets are grouped into chunks of a power of two buckets, the first bucket of an item is chosen
+// by multiply-shift over all buckets, and its alternate bucket is chosen by xor inside the same chunk,
+// so the alternate of the alternate is still the first bucket and items can be deleted.
+// Num of buckets only needs to be a multiple of chunk size, which saves most memory rounded up by
+// power of two tables, see NewFilter
+type VacuumTable struct {
+	SingleTable
+	chunkMask uint
+}
+
+// NewVacuumTable return a VacuumTable
+func NewVacuumTable() *VacuumTable {
+	return &VacuumTable{}
+}
+
+// Init init table, chunk size is the largest power of two dividing num, which is at most vacuumMaxChunk.
+// num should be a power of two or a multiple of the min chunk, see vacuumValidNumBuckets
+func (t *VacuumTable) Init(tagsPerBucket, bitsPerTag, num uint, initialBucketsHint []byte) error {
+	if !vacuumValidNumBuckets(num, tagsPerBucket) {
+		return fmt.Errorf("num of buckets %d is neither a power of two nor a multiple of min chunk", num)
+	}
+	if err := t.SingleTable.Init(tagsPerBucket, bitsPerTag, num, initialBucketsHint); err != nil {
+		return err
+	}
+	chunk := num & -num
+	if chunk > vacuumMaxChunk || chunk == 0 {
+		chunk = vacuumMaxChunk
+	}
+	t.chunkMask = chunk - 1
+	return nil
+}
+
+// vacuumNumBuckets return num of buckets of a VacuumTable which is at least n. It is rounded up to a multiple of
+// a chunk of at most n/16 buckets, so less than 1/16 is wasted, while chunks are as large as possible.
+// Tables smaller than a chunk of vacuumMinChunkSlots are a single chunk of a power of two buckets
+func vacuumNumBuckets(n, tagsPerBucket uint) uint {
+	minChunk := getNextPow2(uint64((vacuumMinChunkSlots + tagsPerBucket - 1) / tagsPerBucket))
+	if n <= minChunk {
+		return getNextPow2(uint64(n) | 1)
+	}
+	chunk := getNextPow2(uint64(n/16)+1) >> 1
+	if chunk < minChunk {
+		chunk = minChunk
+	}
+	if chunk > vacuumMaxChunk {
+		chunk = vacuumMaxChunk
+	}
+	return (n + chunk - 1) / chunk * chunk
+}
+
+// vacuumValidNumBuckets return if num is a num of buckets returned by vacuumNumBuckets,
+// that is a power of two or a multiple of the min chunk
+func vacuumValidNumBuckets(num, tagsPerBucket uint) bool {
+	minChunk := getNextPow2(uint64((vacuumMinChunkSlots + tagsPerBucket - 1) / tagsPerBucket))
+	return num > 0 && (num&(num-1) == 0 || num%minChunk == 0)
+}
+
+// TableType return TableTypeVacuum
+func (t *VacuumTable) TableType() uint {
+	return TableTypeVacuum
+}
+
+// ChunkSize return num of buckets of a chunk, alternate buckets are always in the same chunk
+func (t *VacuumTable) ChunkSize() uint {
+	return t.chunkMask + 1
+}
+
+func (t *VacuumTable) indexHash(hv uint32) uint {
+	return uint(uint64(hv) * uint64(t.numBuckets) >> 32)
+}
+
+func (t *VacuumTable) altIndex(index uint, tag uint32) uint {
+	// 0x5bd1e995 is the hash constant from MurmurHash2
+	return index ^ uint(tag*0x5bd1e995)&t.chunkMask
+}
+
+func (t *VacuumTable) clone() table {
+	nt := *t
+	nt.SingleTable = *t.SingleTable.clone().(*SingleTable)
+	return &nt
+}
+
+// Info return table's info
+func (t *VacuumTable) Info() string {
+	return fmt.Sprintf("VacuumHashtable with tag size: %v bits \n"+
+		"\t\tAssociativity: %v \n"+
+		"\t\tChunk size: %v\n"+
+		"\t\tTotal # of rows: %v\n"+
+		"\t\tTotal # slots: %v\n",
+		t.bitsPerTag, t.kTagsPerBucket, t.ChunkSize(), t.numBuckets, t.SizeInTags())
+}
+
+// Reader returns a reader representing a TableBucket, which is encoded like SingleTable
+func (t *VacuumTable) Reader() (io.Reader, uint) {
+	return t.reader(TableTypeVacuum)
+}
+
+// Decode parse a byte slice into a TableBucket
+func (t *VacuumTable) Decode(b []byte) error {
+	tagsPerBucket := uint(b[1])
+	bitsPerTag := uint(b[2])
+	numBuckets := uint(binary.LittleEndian.Uint32(b[3:]))
+	return t.Init(tagsPerBucket, bitsPerTag, numBuckets, b[7:])
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 23:00
+ */
+
+package cuckoo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// window + num of generations + num of encoded generations
+const windowedHeaderSize = bytesPerUint64 + 2*bytesPerUint32
+
+type generation struct {
+	start  time.Time
+	filter *Filter
+}
+
+// WindowedFilter remember items added in a sliding time window, such as deduplicating events of last minutes.
+// The window is divided into generations, each is a Filter covering window/generations of time.
+// Items are added into the newest generation, and looked up in all generations.
+// When a generation gets older than the window it is dropped as a whole, so an item is remembered
+// for at least window*(generations-1)/generations and at most window after it is added.
+// Expiration happens in every method, driven by the clock. It is not safe for concurrent use
+type WindowedFilter struct {
+	window      time.Duration
+	span        time.Duration
+	numGens     uint
+	now         func() time.Time
+	generations []generation // oldest first
+}
+
+// NewWindowedFilter return a WindowedFilter remembering items of last window, divided into generations,
+// now is the clock, which is time.Now when nil. Generations are filters of the other parameters, see NewFilter
+func NewWindowedFilter(window time.Duration, generations uint, now func() time.Time, tagsPerBucket, bitsPerItem, maxNumKeys, tableType uint) *WindowedFilter {
+	w := newWindowedFilter(window, generations, now)
+	w.generations = []generation{{start: w.now(), filter: NewFilter(tagsPerBucket, bitsPerItem, maxNumKeys, tableType)}}
+	return w
+}
+
+func newWindowedFilter(window time.Duration, generations uint, now func() time.Time) *WindowedFilter {
+	if generations == 0 {
+		generations = 1
+	}
+	if now == nil {
+		now = time.Now
+	}
+	span := window / time.Duration(generations)
+	if span <= 0 {
+		span = 1
+	}
+	return &WindowedFilter{window: window, span: span, numGens: generations, now: now}
+}
+
+// Expire start new generations and drop expired ones according to the clock.
+// It is called by every method, and can be called in idle time to release memory of expired generations
+func (w *WindowedFilter) Expire() {
+	now := w.now()
+	newest := w.generations[len(w.generations)-1]
+	elapsed := now.Sub(newest.start)
+	if elapsed < w.span {
+		return
+	}
+	// keep generations aligned to spans of the first one, even after a long idle time
+	start := newest.start.Add(elapsed / w.span * w.span)
+
+	var recycled *Filter
+	for len(w.generations) > 0 && (uint(len(w.generations)) >= w.numGens || start.Sub(w.generations[0].start) >= w.window) {
+		recycled = w.generations[0].filter
+		w.generations = w.generations[1:]
+	}
+	if recycled == nil {
+		recycled = newest.filter.Clone()
+	}
+	recycled.Reset()
+	w.generations = append(w.generations, generation{start: start, filter: recycled})
+}
+
+// Add add an item into the newest generation, return false when it is full
+func (w *WindowedFilter) Add(item []byte) bool {
+	w.Expire()
+	return w.generations[len(w.generations)-1].filter.Add(item)
+}
+
+// AddUnique add an item into the newest generation, return false when any generation contains it or
+// the newest generation is full
+func (w *WindowedFilter) AddUnique(item []byte) bool {
+	if w.Contain(item) {
+		return false
+	}
+	return w.generations[len(w.generations)-1].filter.Add(item)
+}
+
+// Contain return if any generation contains an item
+func (w *WindowedFilter) Contain(item []byte) bool {
+	w.Expire()
+	// newest first, recent items are more likely looked up
+	for i := len(w.generations) - 1; i >= 0; i-- {
+		if w.generations[i].filter.Contain(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete delete item from the newest generation containing it, return false when item not exist
+func (w *WindowedFilter) Delete(item []byte) bool {
+	w.Expire()
+	for i := len(w.generations) - 1; i >= 0; i-- {
+		if w.generations[i].filter.Delete(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Size return num of items that all generations store
+func (w *WindowedFilter) Size() uint {
+	w.Expire()
+	var n uint
+	for _, g := range w.generations {
+		n += g.filter.Size()
+	}
+	return n
+}
+
+// SizeInBytes return bytes occupancy of all generations' tables
+func (w *WindowedFilter) SizeInBytes() uint {
+	var n uint
+	for _, g := range w.generations {
+		n += g.filter.SizeInBytes()
+	}
+	return n
+}
+
+// Generations return start time of generations, oldest first
+func (w *WindowedFilter) Generations() []time.Time {
+	w.Expire()
+	starts := make([]time.Time, len(w.generations))
+	for i, g := range w.generations {
+		starts[i] = g.start
+	}
+	return starts
+}
+
+// Reset drop all generations and start a new one now
+func (w *WindowedFilter) Reset() {
+	newest := w.generations[len(w.generations)-1].filter
+	newest.Reset()
+	w.generations = []generation{{start: w.now(), filter: newest}}
+}
+
+// Encode returns a byte slice representing the window, generations and their start time
+func (w *WindowedFilter) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	var header [windowedHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[:], uint64(w.window))
+	binary.LittleEndian.PutUint32(header[bytesPerUint64:], uint32(w.numGens))
+	binary.LittleEndian.PutUint32(header[bytesPerUint64+bytesPerUint32:], uint32(len(w.generations)))
+	buf.Write(header[:])
+	for _, g := range w.generations {
+		b, err := g.filter.Encode()
+		if err != nil {
+			return nil, err
+		}
+		var genHeader [bytesPerUint64 + bytesPerUint32]byte
+		binary.LittleEndian.PutUint64(genHeader[:], uint64(g.start.UnixNano()))
+		binary.LittleEndian.PutUint32(genHeader[bytesPerUint64:], uint32(len(b)))
+		buf.Write(genHeader[:])
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeWindowedFilter returns a WindowedFilter from bytes of Encode with clock now, which is time.Now when nil.
+// Generations expired during the time it was stored are dropped on first use
+func DecodeWindowedFilter(b []byte, now func() time.Time) (*WindowedFilter, error) {
+	if len(b) < windowedHeaderSize {
+		return nil, errors.New("unexpected bytes length")
+	}
+	window := time.Duration(binary.LittleEndian.Uint64(b))
+	numGens := uint(binary.LittleEndian.Uint32(b[bytesPerUint64:]))
+	count := binary.LittleEndian.Uint32(b[bytesPerUint64+bytesPerUint32:])
+	if window <= 0 || count == 0 || uint(count) > numGens {
+		return nil, fmt.Errorf("invalid window %v with %d of %d generations", window, count, numGens)
+	}
+	w := newWindowedFilter(window, numGens, now)
+	b = b[windowedHeaderSize:]
+	for i := uint32(0); i < count; i++ {
+		if len(b) < bytesPerUint64+bytesPerUint32 {
+			return nil, errors.New("unexpected bytes length")
+		}
+		start := time.Unix(0, int64(binary.LittleEndian.Uint64(b)))
+		size := uint(binary.LittleEndian.Uint32(b[bytesPerUint64:]))
+		b = b[bytesPerUint64+bytesPerUint32:]
+		if uint(len(b)) < size {
+			return nil, errors.New("unexpected bytes length")
+		}
+		f, err := Decode(b[:size])
+		if err != nil {
+			return nil, fmt.Errorf("generation %d: %w", i, err)
+		}
+		w.generations = append(w.generations, generation{start: start, filter: f})
+		b = b[size:]
+	}
+	return w, nil
+}
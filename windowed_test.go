@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) linvon
+ * Date  2026/10/19 23:00
+ */
+
+package cuckoo
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWindowedFilter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	w := NewWindowedFilter(time.Minute, 3, clock, 4, 12, 1000, TableTypePacked)
+
+	// 3 generations of 20s
+	for i := 0; i < 6; i++ {
+		if !w.AddUnique([]byte("item" + strconv.Itoa(i))) {
+			t.Fatalf("Expected add ok")
+		}
+		now = now.Add(10 * time.Second)
+	}
+	now = time.Unix(1059, 0)
+	if w.AddUnique([]byte("item5")) {
+		t.Fatalf("Expected item already contained")
+	}
+	if len(w.Generations()) != 3 || w.Size() != 6 {
+		t.Fatalf("Expected 3 generations with 6 items, instead %d with %d", len(w.Generations()), w.Size())
+	}
+
+	// first generation started at 1000s is dropped at 1060s
+	if !w.Contain([]byte("item0")) {
+		t.Fatalf("Expected item0 contained before expiration")
+	}
+	b, err := w.Encode()
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	now = now.Add(time.Second)
+	if w.Contain([]byte("item0")) || w.Contain([]byte("item1")) || !w.Contain([]byte("item2")) {
+		t.Fatalf("Expected first generation expired")
+	}
+	if gens := w.Generations(); len(gens) != 3 || !gens[2].Equal(time.Unix(1060, 0)) {
+		t.Fatalf("Unexpected generations %v", gens)
+	}
+
+	// generation timestamps are restored, so expiration continues after decoding
+	now = time.Unix(1059, 0)
+	decoded, err := DecodeWindowedFilter(b, clock)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if decoded.Size() != 6 || !decoded.Contain([]byte("item0")) {
+		t.Fatalf("Expected decoded filter contain all items")
+	}
+	now = time.Unix(1061, 0)
+	if decoded.Contain([]byte("item0")) || decoded.Size() != 4 {
+		t.Fatalf("Expected decoded filter expire first generation, size %d", decoded.Size())
+	}
+
+	// everything expires after a long idle time
+	now = now.Add(time.Hour)
+	if decoded.Size() != 0 || len(decoded.Generations()) != 1 || decoded.Generations()[0].Sub(time.Unix(1000, 0))%(20*time.Second) != 0 {
+		t.Fatalf("Expected one aligned empty generation, instead %v", decoded.Generations())
+	}
+	if !decoded.Add([]byte("a")) || !decoded.Delete([]byte("a")) || decoded.Delete([]byte("a")) {
+		t.Fatalf("Expected add and delete ok")
+	}
+
+	if _, err := DecodeWindowedFilter(b[:len(b)-1], clock); err == nil {
+		t.Fatalf("Expected error decoding truncated bytes")
+	}
+}